@@ -0,0 +1,126 @@
+package nacosstarter
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/acexy/golang-toolkit/crypto/hashing"
+	"github.com/acexy/golang-toolkit/logger"
+	"github.com/nacos-group/nacos-sdk-go/v2/util"
+	"github.com/nacos-group/nacos-sdk-go/v2/vo"
+)
+
+// 停止与查询beta配置依赖服务端的v1开放接口
+const betaConfigPath = "/v1/cs/configs"
+
+// BetaConfig 正在灰度的beta配置
+type BetaConfig struct {
+	Group   string
+	DataId  string
+	Content string
+	Md5     string
+	// 可以获取beta内容的客户端ip
+	Ips []string
+}
+
+type betaConfigInfo struct {
+	DataId  string `json:"dataId"`
+	Group   string `json:"group"`
+	Content string `json:"content"`
+	Md5     string `json:"md5"`
+	BetaIps string `json:"betaIps"`
+}
+
+// PublishBeta 序列化并以beta方式发布配置 仅ips中的客户端可以获取该内容，其他客户端仍获取正式内容
+// 确认无误后使用PublishConfig发布正式内容，或使用StopBeta停止灰度
+func (c *ConfigClient) PublishBeta(dataId string, configType ConfigType, value any, ips ...string) (bool, error) {
+	if len(ips) == 0 {
+		return false, fmt.Errorf("%w: empty beta ips", ErrInvalidParam)
+	}
+	content, err := serializeConfig(configType, value)
+	if err != nil {
+		return false, err
+	}
+	flag, err := c.client.PublishConfig(vo.ConfigParam{
+		DataId:  dataId,
+		Group:   c.group,
+		Content: content,
+		Type:    string(configType),
+		BetaIps: strings.Join(ips, ","),
+	})
+	if err == nil && flag {
+		logger.Logrus().Traceln("published beta config", dataId, "group", c.group, "ips", ips)
+	}
+	return flag, err
+}
+
+// StopBeta 停止灰度 所有客户端恢复获取正式内容
+func (c *ConfigClient) StopBeta(dataId string) (bool, error) {
+	var result restResult[bool]
+	if err := c.m.api.request(http.MethodDelete, betaConfigPath, c.betaParams(dataId), &result); err != nil {
+		return false, err
+	}
+	if err := result.err(); err != nil {
+		return false, err
+	}
+	if result.Data {
+		logger.Logrus().Traceln("stopped beta config", dataId, "group", c.group)
+	}
+	return result.Data, nil
+}
+
+// GetBeta 获取正在灰度的beta配置 没有灰度时返回ErrConfigNotFound
+func (c *ConfigClient) GetBeta(dataId string) (*BetaConfig, error) {
+	var result restResult[*betaConfigInfo]
+	if err := c.m.api.request(http.MethodGet, betaConfigPath, c.betaParams(dataId), &result); err != nil {
+		return nil, err
+	}
+	if err := result.err(); err != nil {
+		return nil, err
+	}
+	if result.Data == nil {
+		return nil, configNotFound(c.group, dataId)
+	}
+	beta := &BetaConfig{Group: c.group, DataId: dataId, Content: result.Data.Content, Md5: result.Data.Md5}
+	for _, ip := range strings.Split(result.Data.BetaIps, ",") {
+		if ip = strings.TrimSpace(ip); ip != "" {
+			beta.Ips = append(beta.Ips, ip)
+		}
+	}
+	if beta.Md5 == "" {
+		beta.Md5 = hashing.Md5Hex(beta.Content)
+	}
+	return beta, nil
+}
+
+// IsBeta 判断当前客户端获取到的内容是否为beta版本
+// 适用于WatchConfig等回调中区分灰度内容，需要额外请求一次服务端
+func (c *ConfigClient) IsBeta(dataId, content string) (bool, error) {
+	beta, err := c.GetBeta(dataId)
+	if err != nil {
+		if errors.Is(err, ErrConfigNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+	// 服务端按客户端上报的本机ip匹配灰度规则
+	if !slices.Contains(beta.Ips, util.LocalIP()) {
+		return false, nil
+	}
+	return beta.Md5 == hashing.Md5Hex(content), nil
+}
+
+func (c *ConfigClient) betaParams(dataId string) url.Values {
+	params := url.Values{}
+	params.Set("beta", "true")
+	params.Set("dataId", dataId)
+	params.Set("group", c.group)
+	if c.namespace != "" {
+		params.Set("tenant", c.namespace)
+	}
+	return params
+}
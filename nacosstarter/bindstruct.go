@@ -0,0 +1,292 @@
+package nacosstarter
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nacos-group/nacos-sdk-go/v2/common/constant"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	tagNacos    = "nacos"
+	tagDefault  = "default"
+	tagRequired = "required"
+)
+
+// BindStruct 根据结构体字段的nacos tag自动加载配置，每个带有tag的字段对应一个配置文件
+//
+// tag格式: `nacos:"group=APP,dataId=db.yaml,type=yaml,watch"`
+//   - group 配置分组 默认DEFAULT_GROUP
+//   - dataId 必填
+//   - type 配置格式 默认根据dataId的扩展名推断
+//   - watch 监听配置变化
+//   - required 配置必须加载成功，否则返回错误
+//   - interpolate/strict 同ConfigFileSetting的Interpolate与StrictPlaceholder
+//
+// 配置结构体内部的字段可以使用 `default:"..."` 在配置中缺少对应的键时设置默认值，显式配置的零值(如false、0)保持不变，
+// 使用 `required:"true"` 要求反序列化后不能为零值，否则本次加载失败
+func BindStruct(target any) ([]*ConfigFileSetting, error) {
+	m, err := getDefaultManager()
+	if err != nil {
+		return nil, err
+	}
+	return m.BindStruct(target)
+}
+
+// BindStruct 使用当前Manager的配置客户端加载结构体配置 详见包级函数BindStruct
+func (m *Manager) BindStruct(target any) ([]*ConfigFileSetting, error) {
+	return bindStruct(m, target, constant.DEFAULT_GROUP)
+}
+
+func bindStruct(m *Manager, target any, defaultGroup string) ([]*ConfigFileSetting, error) {
+	rv := reflect.ValueOf(target)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return nil, fmt.Errorf("%w: target must be a non-nil pointer to struct", ErrInvalidParam)
+	}
+	rv = rv.Elem()
+	rt := rv.Type()
+
+	var groups []string
+	grouped := make(map[string][]*ConfigFileSetting)
+	var required []*ConfigFileSetting
+	var all []*ConfigFileSetting
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		tag, ok := field.Tag.Lookup(tagNacos)
+		if !ok || tag == "-" {
+			continue
+		}
+		if !field.IsExported() {
+			return nil, fmt.Errorf("%w: field %s with nacos tag must be exported", ErrInvalidConfig, field.Name)
+		}
+		setting, group, isRequired, err := parseNacosTag(tag, defaultGroup)
+		if err != nil {
+			return nil, fmt.Errorf("%w: bad nacos tag of field %s: %w", ErrInvalidConfig, field.Name, err)
+		}
+		setting.Value = rv.Field(i).Addr().Interface()
+		if _, ok := grouped[group]; !ok {
+			groups = append(groups, group)
+		}
+		grouped[group] = append(grouped[group], setting)
+		all = append(all, setting)
+		if isRequired {
+			required = append(required, setting)
+		}
+	}
+	if len(all) == 0 {
+		return nil, fmt.Errorf("%w: no field with nacos tag", ErrInvalidConfig)
+	}
+	for _, group := range groups {
+		client, err := m.Config(group)
+		if err != nil {
+			return nil, err
+		}
+		client.LoadAndWatchConfig(grouped[group])
+	}
+	for _, setting := range required {
+		if status := setting.Status(); !status.Loaded {
+			return all, fmt.Errorf("required config %s not loaded: %w", setting.DataId, status.LastError)
+		}
+	}
+	return all, nil
+}
+
+func parseNacosTag(tag, defaultGroup string) (*ConfigFileSetting, string, bool, error) {
+	setting := &ConfigFileSetting{}
+	group := defaultGroup
+	var required bool
+	for _, item := range strings.Split(tag, ",") {
+		key, value, hasValue := strings.Cut(strings.TrimSpace(item), "=")
+		switch key {
+		case "group":
+			group = value
+		case "dataId":
+			setting.DataId = value
+		case "type":
+			setting.Type = ConfigType(value)
+		case "watch":
+			setting.Watch = true
+		case "required":
+			required = true
+		case "interpolate":
+			setting.Interpolate = true
+		case "strict":
+			setting.StrictPlaceholder = true
+		case "":
+			continue
+		default:
+			return nil, "", false, fmt.Errorf("unknown option %s", key)
+		}
+		if hasValue && value == "" {
+			return nil, "", false, fmt.Errorf("empty value of %s", key)
+		}
+	}
+	if setting.DataId == "" {
+		return nil, "", false, errors.New("dataId is required")
+	}
+	if setting.Type == "" {
+		setting.Type = ConfigTypeOf(setting.DataId)
+	}
+	if group == "" {
+		group = defaultGroup
+	}
+	return setting, group, required, nil
+}
+
+// 为配置中缺少对应键且声明了default tag的字段设置默认值，并检查required字段
+// 无法解析出节点树时(如text格式)以字段是否为零值代替判断
+func completeFields(value any, content string, configType ConfigType) error {
+	node, err := documentNode(content, configType)
+	return completeValue(reflect.ValueOf(value), node, err == nil, "")
+}
+
+// 各格式的tag中声明的键名 匹配时不区分大小写
+var fieldKeyTags = []string{"json", "yaml", "toml", "xml", "ini", "properties", "mapstructure"}
+
+// 在映射节点中查找字段对应的值节点
+func fieldNode(node *yaml.Node, field reflect.StructField) (*yaml.Node, bool) {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil, false
+	}
+	names := []string{field.Name}
+	for _, tag := range fieldKeyTags {
+		if name, _, _ := strings.Cut(field.Tag.Get(tag), ","); name != "" && name != "-" {
+			names = append(names, name)
+		}
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		for _, name := range names {
+			if strings.EqualFold(node.Content[i].Value, name) {
+				// 值为null视同未配置
+				value := node.Content[i+1]
+				return value, value.ShortTag() != "!!null"
+			}
+		}
+	}
+	return nil, false
+}
+
+// 未声明键名的嵌入结构体 其字段与外层位于同一层级
+func inlineField(field reflect.StructField) bool {
+	if !field.Anonymous {
+		return false
+	}
+	for _, tag := range fieldKeyTags {
+		if name, _, _ := strings.Cut(field.Tag.Get(tag), ","); name != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// node为当前结构体对应的节点 known为false时不使用节点判断键是否存在
+func completeValue(rv reflect.Value, node *yaml.Node, known bool, path string) error {
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return nil
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil
+	}
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		fv := rv.Field(i)
+		fieldPath := field.Name
+		if path != "" {
+			fieldPath = path + "." + field.Name
+		}
+		child, present := node, true
+		if !inlineField(field) {
+			child, present = fieldNode(node, field)
+		}
+		missing := !present
+		if !known {
+			missing = fv.IsZero()
+		}
+		if def, ok := field.Tag.Lookup(tagDefault); ok && missing {
+			if err := setFieldString(fv, def); err != nil {
+				return fmt.Errorf("%w: bad default value of %s: %w", ErrInvalidConfig, fieldPath, err)
+			}
+		}
+		if required, _ := strconv.ParseBool(field.Tag.Get(tagRequired)); required && fv.IsZero() {
+			return fmt.Errorf("required field %s is missing", fieldPath)
+		}
+		if err := completeValue(fv, child, known, fieldPath); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+func setFieldString(fv reflect.Value, s string) error {
+	if fv.Type() == durationType {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		fv.SetInt(int64(d))
+		return nil
+	}
+	switch fv.Kind() {
+	case reflect.String:
+		fv.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		fv.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(s, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetFloat(n)
+	case reflect.Slice:
+		if fv.Type().Elem().Kind() == reflect.Interface {
+			return fmt.Errorf("unsupported default type %s", fv.Type())
+		}
+		items := strings.Split(s, ",")
+		slice := reflect.MakeSlice(fv.Type(), len(items), len(items))
+		for i, item := range items {
+			if err := setFieldString(slice.Index(i), strings.TrimSpace(item)); err != nil {
+				return err
+			}
+		}
+		fv.Set(slice)
+	case reflect.Ptr:
+		elem := reflect.New(fv.Type().Elem())
+		if err := setFieldString(elem.Elem(), s); err != nil {
+			return err
+		}
+		fv.Set(elem)
+	default:
+		return fmt.Errorf("unsupported default type %s", fv.Type())
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+package nacosstarter
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+type defaultsServer struct {
+	Port    int           `json:"port" yaml:"port" toml:"port" default:"8080"`
+	Enabled bool          `json:"enabled" yaml:"enabled" toml:"enabled" default:"true"`
+	Timeout time.Duration `json:"timeout" yaml:"timeout" toml:"timeout" default:"3s"`
+	Tags    []string      `json:"tags" yaml:"tags" toml:"tags" default:"a,b"`
+}
+
+type DefaultsEmbedded struct {
+	Region string `json:"region" yaml:"region" toml:"region" default:"cn"`
+}
+
+type defaultsConfig struct {
+	DefaultsEmbedded `yaml:",inline"`
+	Name             string          `json:"name" yaml:"name" toml:"name" required:"true"`
+	Server           defaultsServer  `json:"server" yaml:"server" toml:"server"`
+	Backup           *defaultsServer `json:"backup" yaml:"backup" toml:"backup"`
+}
+
+func TestCompleteFieldsDefaults(t *testing.T) {
+	cases := []struct {
+		name       string
+		configType ConfigType
+		content    string
+		want       defaultsServer
+		region     string
+	}{
+		{"yaml missing", ConfigTypeYaml, "name: a\n", defaultsServer{8080, true, 3 * time.Second, []string{"a", "b"}}, "cn"},
+		{"yaml explicit zero", ConfigTypeYaml, "name: a\nregion: \"\"\nserver:\n  port: 0\n  enabled: false\n  timeout: 0s\n  tags: []\n", defaultsServer{0, false, 0, []string{}}, ""},
+		{"yaml null", ConfigTypeYaml, "name: a\nserver:\n  port: ~\n", defaultsServer{8080, true, 3 * time.Second, []string{"a", "b"}}, "cn"},
+		{"json explicit zero", ConfigTypeJson, `{"name": "a", "server": {"port": 0, "enabled": false}}`, defaultsServer{0, false, 3 * time.Second, []string{"a", "b"}}, "cn"},
+		{"json field name", ConfigTypeJson, `{"Name": "a", "Server": {"Port": 0}}`, defaultsServer{0, true, 3 * time.Second, []string{"a", "b"}}, "cn"},
+		{"toml explicit zero", ConfigTypeToml, "name = \"a\"\n[server]\nport = 0\nenabled = false\n", defaultsServer{0, false, 3 * time.Second, []string{"a", "b"}}, "cn"},
+		{"properties explicit zero", ConfigTypeProperties, "name=a\nserver.port=0\nserver.enabled=false\n", defaultsServer{0, false, 3 * time.Second, []string{"a", "b"}}, "cn"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var v defaultsConfig
+			if err := deserializeConfig(c.content, c.configType, &v); err != nil {
+				t.Fatal(err)
+			}
+			if err := completeFields(&v, c.content, c.configType); err != nil {
+				t.Fatal(err)
+			}
+			got := v.Server
+			if got.Port != c.want.Port || got.Enabled != c.want.Enabled || got.Timeout != c.want.Timeout || len(got.Tags) != len(c.want.Tags) {
+				t.Errorf("server = %+v, want %+v", got, c.want)
+			}
+			if v.Region != c.region {
+				t.Errorf("region = %q, want %q", v.Region, c.region)
+			}
+			// 未配置的指针字段保持nil
+			if v.Backup != nil {
+				t.Errorf("backup = %+v", v.Backup)
+			}
+		})
+	}
+}
+
+func TestCompleteFieldsRequired(t *testing.T) {
+	var v defaultsConfig
+	content := "server:\n  port: 1\n"
+	if err := deserializeConfig(content, ConfigTypeYaml, &v); err != nil {
+		t.Fatal(err)
+	}
+	if err := completeFields(&v, content, ConfigTypeYaml); err == nil {
+		t.Error("missing required name but no error")
+	}
+}
+
+func TestConfigFileSettingKeepsExplicitZero(t *testing.T) {
+	var v defaultsConfig
+	f := &ConfigFileSetting{DataId: "app.yaml", Type: ConfigTypeYaml, Value: &v}
+	if _, err := f.apply("name: a\nserver:\n  port: 0\n  enabled: false\n"); err != nil {
+		t.Fatal(err)
+	}
+	if v.Server.Port != 0 || v.Server.Enabled || v.Server.Timeout != 3*time.Second {
+		t.Errorf("server = %+v", v.Server)
+	}
+}
+
+func TestBindStructTagErrors(t *testing.T) {
+	cases := []struct {
+		name   string
+		target any
+		want   error
+	}{
+		{"not pointer", struct{}{}, ErrInvalidParam},
+		{"no tag", &struct{ A string }{}, ErrInvalidConfig},
+		{"unknown option", &struct {
+			A string `nacos:"dataId=a.yaml,bad"`
+		}{}, ErrInvalidConfig},
+		{"missing dataId", &struct {
+			A string `nacos:"group=APP"`
+		}{}, ErrInvalidConfig},
+		{"unexported", &struct {
+			a string `nacos:"dataId=a.yaml"`
+		}{}, ErrInvalidConfig},
+	}
+	for _, c := range cases {
+		if _, err := bindStruct(&Manager{}, c.target, "DEFAULT_GROUP"); !errors.Is(err, c.want) {
+			t.Errorf("%s: err = %v", c.name, err)
+		}
+	}
+
+	var v struct {
+		Port int `yaml:"port" default:"abc"`
+	}
+	if err := completeFields(&v, "a: 1\n", ConfigTypeYaml); !errors.Is(err, ErrInvalidConfig) {
+		t.Errorf("bad default: err = %v", err)
+	}
+}
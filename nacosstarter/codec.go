@@ -0,0 +1,569 @@
+package nacosstarter
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/BurntSushi/toml"
+	"github.com/acexy/golang-toolkit/util/json"
+	"github.com/magiconair/properties"
+	"gopkg.in/ini.v1"
+	"gopkg.in/yaml.v3"
+)
+
+// Decoder 将配置内容反序列化到value value为非nil指针
+type Decoder func(content string, value any) error
+
+// Encoder 将value序列化为配置内容
+type Encoder func(value any) (string, error)
+
+type codec struct {
+	decoder Decoder
+	encoder Encoder
+}
+
+var codecLocker sync.RWMutex
+var codecs = make(map[ConfigType]codec)
+
+func init() {
+	RegisterCodec(ConfigTypeJson, decodeJson, encodeJson)
+	RegisterCodec(ConfigTypeYaml, decodeYaml, encodeYaml)
+	RegisterCodec(ConfigTypeProperties, decodeProperties, encodeProperties)
+	RegisterCodec(ConfigTypeToml, decodeToml, encodeToml)
+	RegisterCodec(ConfigTypeXml, decodeXml, encodeXml)
+	RegisterCodec(ConfigTypeIni, decodeIni, encodeIni)
+	RegisterCodec(ConfigTypeText, decodeText, encodeText)
+}
+
+// RegisterCodec 注册配置格式的编解码器 重复注册将覆盖已有的编解码器(包括内置格式)
+// encoder可以为nil 此时该格式的配置不支持发布
+func RegisterCodec(configType ConfigType, decoder Decoder, encoder Encoder) {
+	if decoder == nil {
+		panic("nil decoder for config type " + string(configType))
+	}
+	codecLocker.Lock()
+	defer codecLocker.Unlock()
+	codecs[configType] = codec{decoder: decoder, encoder: encoder}
+}
+
+func lookupCodec(configType ConfigType) (codec, bool) {
+	codecLocker.RLock()
+	defer codecLocker.RUnlock()
+	c, ok := codecs[configType]
+	return c, ok
+}
+
+func deserializeConfig(content string, configType ConfigType, value any) error {
+	c, ok := lookupCodec(configType)
+	if !ok {
+		return fmt.Errorf("%w: unknown config type %s", ErrInvalidParam, configType)
+	}
+	rv := reflect.ValueOf(value)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return errors.New("value must be a non-nil pointer")
+	}
+	if configType == ConfigTypeText {
+		decrypted, secrets, err := decryptText(content)
+		if err != nil {
+			return newDecodeError(content, configType, err)
+		}
+		// 解密后的明文可能出现在反序列化的错误信息中
+		if err = redactError(c.decoder(decrypted, value), secrets); err != nil {
+			return newDecodeError(decrypted, configType, err)
+		}
+		return nil
+	}
+	// 先按原文反序列化 错误的行列号对应原始内容，再在结果上解密
+	if err := c.decoder(content, value); err != nil {
+		return newDecodeError(content, configType, err)
+	}
+	if err := decryptValue(value, content); err != nil {
+		return newDecodeError(content, configType, err)
+	}
+	return nil
+}
+
+func serializeConfig(configType ConfigType, value any) (string, error) {
+	c, ok := lookupCodec(configType)
+	if !ok {
+		return "", fmt.Errorf("%w: unknown config type %s", ErrInvalidParam, configType)
+	}
+	if c.encoder == nil {
+		return "", fmt.Errorf("%w: config type %s does not support encoding", ErrInvalidParam, configType)
+	}
+	return c.encoder(value)
+}
+
+// 目标是否为通用树结构(*map[string]any或*any) 非结构化格式需要特殊处理
+func treeTarget(value any) (*map[string]any, *any) {
+	switch v := value.(type) {
+	case *map[string]any:
+		return v, nil
+	case *any:
+		return nil, v
+	}
+	return nil, nil
+}
+
+func setTree(value any, tree map[string]any) {
+	m, a := treeTarget(value)
+	if m != nil {
+		*m = tree
+	} else if a != nil {
+		*a = tree
+	}
+}
+
+// 待编码的值是否为通用树结构
+func asTree(value any) (map[string]any, bool) {
+	switch v := value.(type) {
+	case map[string]any:
+		return v, true
+	case *map[string]any:
+		return *v, v != nil
+	case *any:
+		if v != nil {
+			m, ok := (*v).(map[string]any)
+			return m, ok
+		}
+	}
+	return nil, false
+}
+
+// json
+
+func decodeJson(content string, value any) error {
+	return json.ParseJsonError(content, value)
+}
+
+func encodeJson(value any) (string, error) {
+	return json.ToJsonError(value)
+}
+
+// yaml
+
+func decodeYaml(content string, value any) error {
+	elem := reflect.ValueOf(value).Elem()
+	newValue := reflect.New(elem.Type()).Interface()
+	err := yaml.Unmarshal([]byte(content), newValue)
+	if err != nil {
+		return err
+	}
+	elem.Set(reflect.ValueOf(newValue).Elem())
+	return nil
+}
+
+func encodeYaml(value any) (string, error) {
+	b, err := yaml.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// properties
+// 键按照 a.b[0].c 的形式展开为层级结构后按yaml规则绑定，结构体字段使用yaml tag
+
+func decodeProperties(content string, value any) error {
+	loader := properties.Loader{Encoding: properties.UTF8, DisableExpansion: true}
+	p, err := loader.LoadBytes([]byte(content))
+	if err != nil {
+		return err
+	}
+	root := &yaml.Node{Kind: yaml.MappingNode}
+	for _, key := range p.Keys() {
+		segments, err := parseKeyPath(key)
+		if err != nil {
+			// 无法按路径解析的键原样作为顶层键
+			segments = []pathSegment{{key: key}}
+		}
+		v, _ := p.Get(key)
+		setPropertyNode(root, segments, v)
+	}
+	return decodeYamlNode(root, value)
+}
+
+func decodeYamlNode(node *yaml.Node, value any) error {
+	elem := reflect.ValueOf(value).Elem()
+	newValue := reflect.New(elem.Type()).Interface()
+	if err := node.Decode(newValue); err != nil {
+		return err
+	}
+	elem.Set(reflect.ValueOf(newValue).Elem())
+	return nil
+}
+
+func newPropertyNode(next *pathSegment, value string) *yaml.Node {
+	switch {
+	case next == nil:
+		return &yaml.Node{Kind: yaml.ScalarNode, Value: value}
+	case next.isIdx:
+		return &yaml.Node{Kind: yaml.SequenceNode}
+	default:
+		return &yaml.Node{Kind: yaml.MappingNode}
+	}
+}
+
+func setPropertyNode(node *yaml.Node, segments []pathSegment, value string) {
+	for i, s := range segments {
+		var next *pathSegment
+		if i+1 < len(segments) {
+			next = &segments[i+1]
+		}
+		var child *yaml.Node
+		if s.isIdx {
+			if node.Kind != yaml.SequenceNode {
+				node.Kind, node.Content, node.Value = yaml.SequenceNode, nil, ""
+			}
+			for len(node.Content) <= s.index {
+				node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null"})
+			}
+			child = node.Content[s.index]
+			if next == nil || child.Tag == "!!null" {
+				child = newPropertyNode(next, value)
+				node.Content[s.index] = child
+			}
+		} else {
+			if node.Kind != yaml.MappingNode {
+				node.Kind, node.Content, node.Value = yaml.MappingNode, nil, ""
+			}
+			for j := 0; j+1 < len(node.Content); j += 2 {
+				if node.Content[j].Value == s.key {
+					child = node.Content[j+1]
+					if next == nil {
+						child = newPropertyNode(next, value)
+						node.Content[j+1] = child
+					}
+					break
+				}
+			}
+			if child == nil {
+				child = newPropertyNode(next, value)
+				node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: s.key}, child)
+			}
+		}
+		node = child
+	}
+}
+
+func encodeProperties(value any) (string, error) {
+	var node yaml.Node
+	if err := node.Encode(value); err != nil {
+		return "", err
+	}
+	p := properties.NewProperties()
+	p.DisableExpansion = true
+	if err := flattenPropertyNode(p, &node, nil); err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if _, err := p.Write(&buf, properties.UTF8); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+func flattenPropertyNode(p *properties.Properties, node *yaml.Node, path []pathSegment) error {
+	switch node.Kind {
+	case yaml.DocumentNode:
+		for _, c := range node.Content {
+			if err := flattenPropertyNode(p, c, path); err != nil {
+				return err
+			}
+		}
+	case yaml.AliasNode:
+		return flattenPropertyNode(p, node.Alias, path)
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			err := flattenPropertyNode(p, node.Content[i+1], append(path[:len(path):len(path)], pathSegment{key: node.Content[i].Value}))
+			if err != nil {
+				return err
+			}
+		}
+	case yaml.SequenceNode:
+		for i, c := range node.Content {
+			if err := flattenPropertyNode(p, c, append(path[:len(path):len(path)], pathSegment{index: i, isIdx: true})); err != nil {
+				return err
+			}
+		}
+	case yaml.ScalarNode:
+		if len(path) == 0 {
+			return errors.New("properties value must be a struct or map")
+		}
+		v := node.Value
+		if node.ShortTag() == "!!null" {
+			v = ""
+		}
+		_, _, err := p.Set(joinKeyPath(path), v)
+		return err
+	}
+	return nil
+}
+
+// toml
+
+func decodeToml(content string, value any) error {
+	_, err := toml.Decode(content, value)
+	return err
+}
+
+func encodeToml(value any) (string, error) {
+	var buf bytes.Buffer
+	if err := toml.NewEncoder(&buf).Encode(value); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// xml
+// 解码到通用树结构时 根元素作为顶层键，属性以@为前缀，同时包含子元素与文本的元素文本键为#text，重复的子元素转为数组
+
+func decodeXml(content string, value any) error {
+	if m, a := treeTarget(value); m == nil && a == nil {
+		return xml.Unmarshal([]byte(content), value)
+	}
+	decoder := xml.NewDecoder(strings.NewReader(content))
+	tree := make(map[string]any)
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if start, ok := token.(xml.StartElement); ok {
+			v, err := decodeXmlElement(decoder, start)
+			if err != nil {
+				return err
+			}
+			appendTreeValue(tree, start.Name.Local, v)
+		}
+	}
+	setTree(value, tree)
+	return nil
+}
+
+func decodeXmlElement(decoder *xml.Decoder, start xml.StartElement) (any, error) {
+	children := make(map[string]any)
+	for _, attr := range start.Attr {
+		children["@"+attr.Name.Local] = attr.Value
+	}
+	var text strings.Builder
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			v, err := decodeXmlElement(decoder, t)
+			if err != nil {
+				return nil, err
+			}
+			appendTreeValue(children, t.Name.Local, v)
+		case xml.CharData:
+			text.Write(t)
+		case xml.EndElement:
+			s := strings.TrimSpace(text.String())
+			if len(children) == 0 {
+				return s, nil
+			}
+			if s != "" {
+				children["#text"] = s
+			}
+			return children, nil
+		}
+	}
+}
+
+func appendTreeValue(tree map[string]any, key string, value any) {
+	exists, ok := tree[key]
+	if !ok {
+		tree[key] = value
+		return
+	}
+	if list, ok := exists.([]any); ok {
+		tree[key] = append(list, value)
+		return
+	}
+	tree[key] = []any{exists, value}
+}
+
+func encodeXml(value any) (string, error) {
+	tree, ok := asTree(value)
+	if !ok {
+		b, err := xml.MarshalIndent(value, "", "  ")
+		if err != nil {
+			return "", err
+		}
+		return string(b), nil
+	}
+	var buf bytes.Buffer
+	for _, key := range sortedKeys(tree) {
+		if err := encodeXmlElement(&buf, key, tree[key]); err != nil {
+			return "", err
+		}
+	}
+	return buf.String(), nil
+}
+
+func encodeXmlElement(buf *bytes.Buffer, name string, value any) error {
+	switch v := value.(type) {
+	case []any:
+		for _, item := range v {
+			if err := encodeXmlElement(buf, name, item); err != nil {
+				return err
+			}
+		}
+		return nil
+	case map[string]any:
+		buf.WriteString("<" + name)
+		var keys []string
+		for _, key := range sortedKeys(v) {
+			if strings.HasPrefix(key, "@") {
+				buf.WriteString(" " + key[1:] + `="`)
+				if err := xml.EscapeText(buf, []byte(fmt.Sprint(v[key]))); err != nil {
+					return err
+				}
+				buf.WriteString(`"`)
+			} else {
+				keys = append(keys, key)
+			}
+		}
+		buf.WriteString(">")
+		for _, key := range keys {
+			if key == "#text" {
+				if err := xml.EscapeText(buf, []byte(fmt.Sprint(v[key]))); err != nil {
+					return err
+				}
+				continue
+			}
+			if err := encodeXmlElement(buf, key, v[key]); err != nil {
+				return err
+			}
+		}
+		buf.WriteString("</" + name + ">")
+		return nil
+	default:
+		buf.WriteString("<" + name + ">")
+		if v != nil {
+			if err := xml.EscapeText(buf, []byte(fmt.Sprint(v))); err != nil {
+				return err
+			}
+		}
+		buf.WriteString("</" + name + ">")
+		return nil
+	}
+}
+
+// ini
+// 解码到通用树结构时 默认分区的键位于顶层，其他分区作为嵌套对象
+
+func decodeIni(content string, value any) error {
+	file, err := ini.Load([]byte(content))
+	if err != nil {
+		return err
+	}
+	if m, a := treeTarget(value); m == nil && a == nil {
+		return file.MapTo(value)
+	}
+	tree := make(map[string]any)
+	for _, section := range file.Sections() {
+		target := tree
+		if section.Name() != ini.DefaultSection {
+			target = make(map[string]any)
+			tree[section.Name()] = target
+		}
+		for _, key := range section.Keys() {
+			target[key.Name()] = key.Value()
+		}
+	}
+	setTree(value, tree)
+	return nil
+}
+
+func encodeIni(value any) (string, error) {
+	file := ini.Empty()
+	if tree, ok := asTree(value); ok {
+		for _, key := range sortedKeys(tree) {
+			if section, ok := tree[key].(map[string]any); ok {
+				s, err := file.NewSection(key)
+				if err != nil {
+					return "", err
+				}
+				for _, k := range sortedKeys(section) {
+					if _, err = s.NewKey(k, fmt.Sprint(section[k])); err != nil {
+						return "", err
+					}
+				}
+				continue
+			}
+			if _, err := file.Section(ini.DefaultSection).NewKey(key, fmt.Sprint(tree[key])); err != nil {
+				return "", err
+			}
+		}
+	} else {
+		// ReflectFrom只接受结构体指针
+		rv := reflect.ValueOf(value)
+		if rv.IsValid() && rv.Kind() != reflect.Ptr {
+			ptr := reflect.New(rv.Type())
+			ptr.Elem().Set(rv)
+			value = ptr.Interface()
+		}
+		if err := ini.ReflectFrom(file, value); err != nil {
+			return "", err
+		}
+	}
+	var buf bytes.Buffer
+	if _, err := file.WriteTo(&buf); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// text
+
+func decodeText(content string, value any) error {
+	switch v := value.(type) {
+	case *string:
+		*v = content
+	case *[]byte:
+		*v = []byte(content)
+	case *any:
+		*v = content
+	default:
+		return fmt.Errorf("text config can only be decoded into *string or *[]byte, got %T", value)
+	}
+	return nil
+}
+
+func encodeText(value any) (string, error) {
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case *string:
+		return *v, nil
+	case []byte:
+		return string(v), nil
+	case fmt.Stringer:
+		return v.String(), nil
+	}
+	return fmt.Sprint(value), nil
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,97 @@
+package nacosstarter
+
+import (
+	"reflect"
+	"testing"
+)
+
+type codecDB struct {
+	Host string `json:"host" yaml:"host" toml:"host" xml:"host" ini:"host"`
+	Port int    `json:"port" yaml:"port" toml:"port" xml:"port" ini:"port"`
+}
+
+type codecConfig struct {
+	Name  string   `json:"name" yaml:"name" toml:"name" xml:"name" ini:"name"`
+	Debug bool     `json:"debug" yaml:"debug" toml:"debug" xml:"debug" ini:"debug"`
+	Tags  []string `json:"tags" yaml:"tags" toml:"tags" xml:"tags" ini:"tags"`
+	DB    codecDB  `json:"db" yaml:"db" toml:"db" xml:"db" ini:"db"`
+}
+
+func TestCodecRoundTrip(t *testing.T) {
+	want := codecConfig{Name: "app", Debug: true, Tags: []string{"a", "b"}, DB: codecDB{Host: "db.local", Port: 3306}}
+	for _, configType := range []ConfigType{ConfigTypeProperties, ConfigTypeXml, ConfigTypeIni, ConfigTypeToml} {
+		t.Run(string(configType), func(t *testing.T) {
+			content, err := serializeConfig(configType, want)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got codecConfig
+			if err = deserializeConfig(content, configType, &got); err != nil {
+				t.Fatalf("%v\n%s", err, content)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("got %+v, want %+v\n%s", got, want, content)
+			}
+		})
+	}
+}
+
+func TestCodecDecodeTree(t *testing.T) {
+	cases := []struct {
+		configType ConfigType
+		content    string
+		want       map[string]any
+	}{
+		{
+			configType: ConfigTypeProperties,
+			content:    "name=app\ndb.port=3306\ntags[0]=a\ntags[1]=b\n",
+			want:       map[string]any{"name": "app", "db": map[string]any{"port": 3306}, "tags": []any{"a", "b"}},
+		},
+		{
+			configType: ConfigTypeXml,
+			content:    `<config id="1"><name>app</name><tags>a</tags><tags>b</tags><db>local<port>3306</port></db></config>`,
+			want: map[string]any{"config": map[string]any{
+				"@id":  "1",
+				"name": "app",
+				"tags": []any{"a", "b"},
+				"db":   map[string]any{"#text": "local", "port": "3306"},
+			}},
+		},
+		{
+			configType: ConfigTypeIni,
+			content:    "name = app\n\n[db]\nport = 3306\n",
+			want:       map[string]any{"name": "app", "db": map[string]any{"port": "3306"}},
+		},
+		{
+			configType: ConfigTypeToml,
+			content:    "name = \"app\"\ntags = [\"a\", \"b\"]\n\n[db]\nport = 3306\n",
+			want:       map[string]any{"name": "app", "db": map[string]any{"port": int64(3306)}, "tags": []any{"a", "b"}},
+		},
+	}
+	for _, c := range cases {
+		t.Run(string(c.configType), func(t *testing.T) {
+			var tree map[string]any
+			if err := deserializeConfig(c.content, c.configType, &tree); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(tree, c.want) {
+				t.Errorf("got %#v, want %#v", tree, c.want)
+			}
+		})
+	}
+}
+
+func TestCodecDecodeInvalid(t *testing.T) {
+	cases := map[ConfigType]string{
+		ConfigTypeProperties: `name=\uZZZZ`,
+		ConfigTypeXml:        "<config><name>app</config>",
+		ConfigTypeIni:        "[db\nport = 1",
+		ConfigTypeToml:       "name = ",
+	}
+	for configType, content := range cases {
+		var tree map[string]any
+		if err := deserializeConfig(content, configType, &tree); err == nil {
+			t.Errorf("%s: expected error, got %#v", configType, tree)
+		}
+	}
+}
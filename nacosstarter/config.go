@@ -1,8 +1,13 @@
 package nacosstarter
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/acexy/golang-toolkit/crypto/hashing"
 	"github.com/acexy/golang-toolkit/logger"
@@ -17,74 +22,430 @@ type ConfigType string
 type ConfigChangeData func(namespace, group, dataId, data string)
 
 const (
-	ConfigTypeJson ConfigType = "json"
-	ConfigTypeYaml ConfigType = "yaml"
+	ConfigTypeJson       ConfigType = "json"
+	ConfigTypeYaml       ConfigType = "yaml"
+	ConfigTypeProperties ConfigType = "properties"
+	ConfigTypeToml       ConfigType = "toml"
+	ConfigTypeXml        ConfigType = "xml"
+	ConfigTypeIni        ConfigType = "ini"
+	ConfigTypeText       ConfigType = "text"
+)
+
+const (
+	// UpdateConfig 最大尝试次数
+	updateMaxAttempts = 5
+	// UpdateConfig 首次重试等待时间 之后每次翻倍
+	updateBackoff = 100 * time.Millisecond
+	// UpdateConfig 最大重试等待时间
+	updateMaxBackoff = 2 * time.Second
 )
 
 // GetConfigRawContent 获取指定配置的源文件内容
+// 配置不存在时返回空字符串，dataId为空时返回的错误可以匹配ErrInvalidParam，请求失败时可以匹配ErrServerUnavailable
 func (c *ConfigClient) GetConfigRawContent(dataId string) (string, error) {
-	return configInstance.GetConfig(vo.ConfigParam{DataId: dataId, Group: c.group})
+	// 参数在调用sdk前校验 sdk返回的错误均来自服务端请求
+	if dataId == "" {
+		return "", invalidParam("dataId")
+	}
+	content, err := c.client.GetConfig(vo.ConfigParam{DataId: dataId, Group: c.group})
+	if err != nil {
+		return "", serverError(err)
+	}
+	return content, nil
 }
 
 // GetConfig 获取指定文件内容并反序列化
+// 返回的错误可以匹配ErrConfigNotFound、ErrInvalidParam、ErrServerUnavailable与ErrDecode
 func (c *ConfigClient) GetConfig(dataId string, configType ConfigType, value any) error {
+	_, err := c.GetConfigWithMd5(dataId, configType, value)
+	return err
+}
+
+// GetConfigWithMd5 获取指定文件内容并反序列化 同时返回原始内容的md5
+// 返回的md5可用于PublishConfigCas进行CAS更新
+func (c *ConfigClient) GetConfigWithMd5(dataId string, configType ConfigType, value any) (string, error) {
 	raw, err := c.GetConfigRawContent(dataId)
 	if err != nil {
-		return nil
+		return "", err
+	}
+	if raw == "" {
+		return "", configNotFound(c.group, dataId)
+	}
+	err = deserializeConfig(raw, configType, value)
+	if err != nil {
+		return "", withConfigSource(err, c.group, dataId)
+	}
+	return hashing.Md5Hex(raw), nil
+}
+
+// PublishConfig 序列化并发布配置
+func (c *ConfigClient) PublishConfig(dataId string, configType ConfigType, value any) (bool, error) {
+	return c.PublishConfigCas(dataId, configType, value, "")
+}
+
+// PublishConfigCas 序列化并以CAS方式发布配置
+// casMd5为最近一次读取到的内容md5 若服务端内容已被其他写入方修改则发布失败，返回的错误可以匹配ErrConfigConflict; casMd5为空时不做校验
+// 其他请求失败时返回的错误可以匹配ErrServerUnavailable
+func (c *ConfigClient) PublishConfigCas(dataId string, configType ConfigType, value any, casMd5 string) (bool, error) {
+	if dataId == "" {
+		return false, invalidParam("dataId")
+	}
+	content, err := serializeConfig(configType, value)
+	if err != nil {
+		return false, err
+	}
+	flag, err := c.client.PublishConfig(vo.ConfigParam{
+		DataId:  dataId,
+		Group:   c.group,
+		Content: content,
+		Type:    string(configType),
+		CasMd5:  casMd5,
+	})
+	switch {
+	case err != nil && isCasConflict(err):
+		return false, fmt.Errorf("%w: %w", ErrConfigConflict, err)
+	case err != nil:
+		return false, serverError(err)
+	case flag:
+		logger.Logrus().Traceln("published config", dataId, "group", c.group)
+	}
+	return flag, nil
+}
+
+// UpdateConfig 以乐观锁方式修改结构化配置
+// 读取并反序列化当前配置后执行mutate，再将结果序列化并携带读取时的md5进行CAS发布
+// 若发布时配置已被其他写入方修改则退避后重新读取并再次执行mutate，多次冲突后返回的错误可以匹配ErrConfigConflict
+// 网络、鉴权等其他发布错误不会重试; mutate返回错误时放弃本次修改，mutate可能被执行多次，不应包含副作用
+// 配置不存在时mutate将收到零值，此时服务端无法进行CAS校验，并发创建同一配置时后发布的内容会覆盖先发布的内容
+func UpdateConfig[T any](c *ConfigClient, dataId string, configType ConfigType, mutate func(current *T) error) error {
+	backoff := updateBackoff
+	var lastErr error
+	for attempt := 1; attempt <= updateMaxAttempts; attempt++ {
+		raw, err := c.GetConfigRawContent(dataId)
+		if err != nil {
+			return err
+		}
+		current := new(T)
+		var casMd5 string
+		if raw != "" {
+			if err = deserializeConfig(raw, configType, current); err != nil {
+				return withConfigSource(err, c.group, dataId)
+			}
+			casMd5 = hashing.Md5Hex(raw)
+		}
+		if err = mutate(current); err != nil {
+			return err
+		}
+		flag, err := c.PublishConfigCas(dataId, configType, current, casMd5)
+		if err == nil && flag {
+			return nil
+		}
+		if err != nil && !errors.Is(err, ErrConfigConflict) {
+			return err
+		}
+		if err == nil {
+			err = fmt.Errorf("%w: publish config %s rejected", ErrConfigConflict, dataId)
+		}
+		lastErr = err
+		logger.Logrus().WithError(err).Warnln("update config conflict dataId:", dataId, "attempt:", attempt)
+		if attempt < updateMaxAttempts {
+			time.Sleep(backoff)
+			backoff = min(backoff*2, updateMaxBackoff)
+		}
 	}
-	return deserializeConfig(raw, configType, value)
+	return fmt.Errorf("update config %s failed after %d attempts: %w", dataId, updateMaxAttempts, lastErr)
+}
+
+// 服务端以失败响应拒绝CAS发布 如 "Cas publish fail, server md5 may have changed."
+func isCasConflict(err error) bool {
+	return strings.Contains(strings.ToLower(err.Error()), "cas publish fail")
+}
+
+// DeleteConfig 删除指定配置 请求失败时返回的错误可以匹配ErrServerUnavailable
+func (c *ConfigClient) DeleteConfig(dataId string) (bool, error) {
+	if dataId == "" {
+		return false, invalidParam("dataId")
+	}
+	flag, err := c.client.DeleteConfig(vo.ConfigParam{DataId: dataId, Group: c.group})
+	if err != nil {
+		return false, serverError(err)
+	}
+	if flag {
+		logger.Logrus().Traceln("deleted config", dataId, "group", c.group)
+	}
+	return flag, nil
 }
 
 // WatchConfig 监听文件变化
+// 同一个dataId可以被多次监听，所有订阅者共享同一个底层监听，返回的watchId仅对应当前订阅者
 func (c *ConfigClient) WatchConfig(dataId string, watch func(namespace, group, dataId, data string)) (string, error) {
+	if dataId == "" {
+		return "", invalidParam("dataId")
+	}
 	c.mu.Lock()
 	defer c.mu.Unlock()
-	param := vo.ConfigParam{DataId: dataId, Group: c.group}
-	watchId := hashing.Md5Hex(dataId + c.group)
-	_, ok := c.watched[watchId]
+	listenKey := hashing.Md5Hex(dataId + c.group)
+	c.watchSeq++
+	watchId := listenKey + ":" + strconv.FormatUint(c.watchSeq, 10)
+	w, ok := c.watched[listenKey]
 	if ok {
-		return "", fmt.Errorf("duplicate watch %s", param.DataId)
+		w.subscribers = append(w.subscribers, configSubscriber{id: watchId, watch: watch})
+		c.watchIds[watchId] = listenKey
+		c.m.observer.ConfigWatchChanged(c.namespace, c.group, dataId, 1)
+		return watchId, nil
 	}
-	param.OnChange = func(namespace, group, dataId, data string) {
-		watch(namespace, group, dataId, data)
+	w = &configWatch{param: vo.ConfigParam{DataId: dataId, Group: c.group}}
+	w.subscribers = []configSubscriber{{id: watchId, watch: watch}}
+	w.param.OnChange = func(namespace, group, dataId, data string) {
+		c.mu.Lock()
+		subscribers := append([]configSubscriber{}, w.subscribers...)
+		c.mu.Unlock()
+		for _, s := range subscribers {
+			s.watch(namespace, group, dataId, data)
+		}
+	}
+	if err := c.client.ListenConfig(w.param); err != nil {
+		return "", serverError(err)
 	}
-	c.watched[watchId] = &param
-	return watchId, configInstance.ListenConfig(param)
+	c.watched[listenKey] = w
+	c.watchIds[watchId] = listenKey
+	c.m.observer.ConfigWatchChanged(c.namespace, c.group, dataId, 1)
+	return watchId, nil
 }
 
 // UnwatchConfig 取消监听文件变化
+// 仅取消watchId对应的订阅者，最后一个订阅者取消后才会取消底层监听
 func (c *ConfigClient) UnwatchConfig(watchId string) error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
-	v, ok := c.watched[watchId]
-	if ok {
-		err := configInstance.CancelListenConfig(*v)
-		if err == nil {
-			delete(c.watched, watchId)
+	listenKey, ok := c.watchIds[watchId]
+	if !ok {
+		return fmt.Errorf("%w %s", ErrUnknownWatch, watchId)
+	}
+	w := c.watched[listenKey]
+	for i, s := range w.subscribers {
+		if s.id == watchId {
+			w.subscribers = append(w.subscribers[:i:i], w.subscribers[i+1:]...)
+			break
 		}
-		return err
 	}
-	return errors.New("bad watchId")
+	delete(c.watchIds, watchId)
+	c.m.observer.ConfigWatchChanged(c.namespace, c.group, w.param.DataId, -1)
+	if len(w.subscribers) > 0 {
+		return nil
+	}
+	err := c.client.CancelListenConfig(w.param)
+	if err == nil {
+		delete(c.watched, listenKey)
+	}
+	return serverError(err)
+}
+
+// ConfigEvent 配置变化事件
+type ConfigEvent struct {
+	Namespace string
+	Group     string
+	DataId    string
+	Content   string
+}
+
+// Watch 以channel的形式监听文件变化
+// ctx结束后将自动取消监听并关闭channel
+// 消费方读取不及时时丢弃较早的事件，事件顺序不保证与变更顺序一致，需要时以最后读取的内容为准
+func (c *ConfigClient) Watch(ctx context.Context, dataId string) (<-chan ConfigEvent, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	events := newEventChannel[ConfigEvent](ctx)
+	watchId, err := c.WatchConfig(dataId, func(namespace, group, dataId, data string) {
+		events.send(ConfigEvent{Namespace: namespace, Group: group, DataId: dataId, Content: data})
+	})
+	if err != nil {
+		return nil, err
+	}
+	events.closeOnDone(func() {
+		if err := c.UnwatchConfig(watchId); err != nil {
+			logger.Logrus().WithError(err).Warnln("cant unwatch config:", dataId, "group:", c.group)
+		}
+	})
+	return events.ch, nil
 }
 
 // LoadAndWatchConfig 获取并监听配置变化
+// 每次变更都会先反序列化到全新的副本并完成校验，成功后才替换Value，失败时保留上一次有效的值
+// 与上一次成功应用的内容md5相同的变更通知将被忽略
 func (c *ConfigClient) LoadAndWatchConfig(configFiles []*ConfigFileSetting) {
 	if len(configFiles) == 0 {
 		logger.Logrus().Warningln("empty config file")
 		return
 	}
 	for _, f := range configFiles {
-		err := c.GetConfig(f.DataId, f.Type, f.Value)
+		f.group = c.group
+		reload := c.reloader(f.DataId, f.apply, func(err error) {
+			f.fail(fmt.Errorf("cant reload config file %s: %w", f.DataId, err))
+		})
+		raw, stale, err := c.getConfigOrSnapshot(f.DataId)
+		if err == nil {
+			_, err = f.apply(raw)
+		}
+		c.observeApplied(f.DataId, false, err)
 		if err != nil {
-			logger.Logrus().Errorln("cant load config file:", f.DataId, err)
+			f.fail(fmt.Errorf("cant load config file %s: %w", f.DataId, err))
+		} else if stale {
+			f.markStale()
+			c.recoverFromServer(f.DataId, reload)
+		} else {
+			c.saveSnapshot(f.DataId, raw)
 		}
 		if f.Watch {
-			_, err = c.WatchConfig(f.DataId, func(namespace, group, dataId, data string) {
-				err = deserializeConfig(data, f.Type, f.Value)
-				if err != nil {
-					logger.Logrus().WithError(err).Error("cant deserialize content:", data)
-				}
-			})
+			if _, err = c.watchReload(f.DataId, f.Debounce, reload); err != nil {
+				f.fail(fmt.Errorf("cant watch config file %s: %w", f.DataId, err))
+			}
+		}
+	}
+}
+
+// 构建配置变更的处理流程 apply成功后上报结果并保存快照，失败时交给fail处理
+// 内容未变化的通知直接忽略
+func (c *ConfigClient) reloader(dataId string, apply func(content string) (bool, error), fail func(err error)) func(content string) error {
+	return func(content string) error {
+		changed, err := apply(content)
+		if err == nil && !changed {
+			return nil
+		}
+		c.observeApplied(dataId, true, err)
+		if err != nil {
+			fail(err)
+			return err
+		}
+		c.saveSnapshot(dataId, content)
+		return nil
+	}
+}
+
+// 监听配置变化并在合并窗口结束后执行reload window<=0时每次变化立即执行
+func (c *ConfigClient) watchReload(dataId string, window time.Duration, reload func(content string) error) (string, error) {
+	onChange := debounce(window, func(content string) {
+		_ = reload(content)
+	})
+	return c.WatchConfig(dataId, func(namespace, group, dataId, data string) {
+		onChange(data)
+	})
+}
+
+// Validatable 实现该接口的配置类型在加载和热更新时将被校验，校验失败的内容不会被应用
+type Validatable interface {
+	Validate() error
+}
+
+// ConfigStatus 配置加载状态
+type ConfigStatus struct {
+	// 是否已成功加载过
+	Loaded bool
+	// 成功应用的次数(包含首次加载)
+	Applied uint64
+	// 失败次数(包含获取、反序列化与校验失败)
+	Failures uint64
+	// 最近一次成功应用的时间
+	LastApplied time.Time
+	// 最近一次失败的原因与时间
+	LastError     error
+	LastErrorTime time.Time
+	// 当前值来自本地快照，尚未从服务端获取到最新内容
+	Stale bool
+}
+
+// Status 获取当前配置的加载状态
+func (s *ConfigFileSetting) Status() ConfigStatus {
+	s.statusLocker.Lock()
+	defer s.statusLocker.Unlock()
+	return s.status
+}
+
+// 解析占位符后反序列化到新副本并校验 通过后替换Value
+// 内容与上一次成功应用的相同时changed为false
+func (s *ConfigFileSetting) apply(content string) (changed bool, err error) {
+	rv := reflect.ValueOf(s.Value)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return false, errors.New("value must be a non-nil pointer")
+	}
+	if content == "" {
+		return false, configNotFound(s.group, s.DataId)
+	}
+	contentMd5 := hashing.Md5Hex(content)
+	// sdk监听、合并窗口与快照恢复可能并发应用 从比较md5到替换Value需要串行执行
+	s.applyLocker.Lock()
+	defer s.applyLocker.Unlock()
+	s.statusLocker.Lock()
+	if contentMd5 == s.md5 {
+		// 重连或缓存重载时sdk可能通知相同的内容
+		s.status.Stale = false
+		s.statusLocker.Unlock()
+		return false, nil
+	}
+	s.statusLocker.Unlock()
+	if s.Interpolate {
+		if content, err = Interpolate(content, s.Type, s.StrictPlaceholder); err != nil {
+			return false, err
+		}
+	}
+	fresh := reflect.New(rv.Elem().Type())
+	if err = deserializeConfig(content, s.Type, fresh.Interface()); err != nil {
+		return false, withConfigSource(err, s.group, s.DataId)
+	}
+	if err = completeFields(fresh.Interface(), content, s.Type); err != nil {
+		return false, err
+	}
+	if err = validateConfig(fresh.Interface(), s.Validate); err != nil {
+		return false, err
+	}
+	rv.Elem().Set(fresh.Elem())
+
+	s.statusLocker.Lock()
+	defer s.statusLocker.Unlock()
+	s.md5 = contentMd5
+	s.status.Loaded = true
+	s.status.Stale = false
+	s.status.Applied++
+	s.status.LastApplied = time.Now()
+	return true, nil
+}
+
+func (s *ConfigFileSetting) clearStale() {
+	s.statusLocker.Lock()
+	defer s.statusLocker.Unlock()
+	s.status.Stale = false
+}
+
+func (s *ConfigFileSetting) markStale() {
+	s.statusLocker.Lock()
+	defer s.statusLocker.Unlock()
+	s.status.Stale = true
+}
+
+func (s *ConfigFileSetting) fail(err error) {
+	s.statusLocker.Lock()
+	s.status.Failures++
+	s.status.LastError = err
+	s.status.LastErrorTime = time.Now()
+	s.statusLocker.Unlock()
+
+	logger.Logrus().WithError(err).Errorln("config keeps last good value")
+	if s.OnError != nil {
+		s.OnError(s.DataId, err)
+	}
+}
+
+func validateConfig(value any, validate func(value any) error) error {
+	if v, ok := value.(Validatable); ok {
+		if err := v.Validate(); err != nil {
+			return fmt.Errorf("validate failed: %w", err)
+		}
+	}
+	if validate != nil {
+		if err := validate(value); err != nil {
+			return fmt.Errorf("validate failed: %w", err)
 		}
 	}
+	return nil
 }
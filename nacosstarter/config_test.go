@@ -0,0 +1,376 @@
+package nacosstarter
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/acexy/golang-toolkit/crypto/hashing"
+	"github.com/nacos-group/nacos-sdk-go/v2/vo"
+)
+
+func TestConfigFileSettingConcurrentApply(t *testing.T) {
+	var v struct {
+		N int `json:"n"`
+	}
+	f := &ConfigFileSetting{DataId: "n.json", Type: ConfigTypeJson, Value: &v}
+	var wg sync.WaitGroup
+	for i := 1; i <= 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := f.apply(fmt.Sprintf(`{"n": %d}`, i)); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	status := f.Status()
+	if status.Applied != 50 {
+		t.Errorf("applied = %d", status.Applied)
+	}
+	if f.md5 == "" || v.N == 0 {
+		t.Errorf("n = %d md5 = %q", v.N, f.md5)
+	}
+	// 相同内容不会重复应用
+	last := fmt.Sprintf(`{"n": %d}`, v.N)
+	if changed, err := f.apply(last); err != nil || changed {
+		t.Fatal(changed, err)
+	}
+	if f.Status().Applied != 50 {
+		t.Errorf("identical content applied again")
+	}
+}
+
+// 在fakeConfigClient的基础上按服务端的CAS语义发布配置
+type casConfigClient struct {
+	*fakeConfigClient
+	// 非空时PublishConfig直接返回该错误
+	publishErr error
+	publishes  int
+}
+
+func newCasConfigClient(contents map[string]string) *casConfigClient {
+	return &casConfigClient{fakeConfigClient: newFakeConfigClient(contents)}
+}
+
+func (f *casConfigClient) PublishConfig(param vo.ConfigParam) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.publishes++
+	if f.publishErr != nil {
+		return false, f.publishErr
+	}
+	if param.CasMd5 != "" && hashing.Md5Hex(f.contents[param.DataId]) != param.CasMd5 {
+		return false, errors.New("Cas publish fail, server md5 may have changed.")
+	}
+	f.contents[param.DataId] = param.Content
+	return true, nil
+}
+
+// 模拟服务端推送变更
+func (f *fakeConfigClient) notify(dataId, content string) {
+	f.mu.Lock()
+	f.contents[dataId] = content
+	listener := f.listeners[dataId]
+	f.mu.Unlock()
+	listener("", "DEFAULT_GROUP", dataId, content)
+}
+
+// 记录配置应用结果的Observer
+type recordObserver struct {
+	NopObserver
+	mu      sync.Mutex
+	applies []bool
+}
+
+func (o *recordObserver) ConfigApplied(namespace, group, dataId string, reload bool, err error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.applies = append(o.applies, reload)
+}
+
+type counterConfig struct {
+	Count int `json:"count"`
+}
+
+func TestUpdateConfig(t *testing.T) {
+	fake := newCasConfigClient(map[string]string{})
+	c := newTestConfigClient(fake)
+	increment := func(current *counterConfig) error {
+		current.Count++
+		return nil
+	}
+	for i := 0; i < 3; i++ {
+		if err := UpdateConfig(c, "counter.json", ConfigTypeJson, increment); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var v counterConfig
+	if err := c.GetConfig("counter.json", ConfigTypeJson, &v); err != nil || v.Count != 3 {
+		t.Fatalf("count = %d err = %v", v.Count, err)
+	}
+
+	// 读取后被其他写入方修改 CAS失败后基于最新内容重试
+	conflicted := false
+	err := UpdateConfig(c, "counter.json", ConfigTypeJson, func(current *counterConfig) error {
+		if !conflicted {
+			conflicted = true
+			fake.contents["counter.json"] = `{"count": 10}`
+		}
+		current.Count++
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = c.GetConfig("counter.json", ConfigTypeJson, &v); err != nil || v.Count != 11 {
+		t.Fatalf("count = %d err = %v", v.Count, err)
+	}
+}
+
+func TestUpdateConfigErrors(t *testing.T) {
+	fake := newCasConfigClient(map[string]string{"counter.json": `{"count": 1}`})
+	c := newTestConfigClient(fake)
+	fake.publishErr = errors.New("user not found")
+	err := UpdateConfig(c, "counter.json", ConfigTypeJson, func(current *counterConfig) error { return nil })
+	if err == nil || fake.publishes != 1 {
+		t.Fatalf("publishes = %d err = %v, want no retry", fake.publishes, err)
+	}
+
+	fake.publishErr = errors.New("Cas publish fail, server md5 may have changed.")
+	fake.publishes = 0
+	err = UpdateConfig(c, "counter.json", ConfigTypeJson, func(current *counterConfig) error { return nil })
+	if !errors.Is(err, ErrConfigConflict) || fake.publishes != updateMaxAttempts {
+		t.Fatalf("publishes = %d err = %v", fake.publishes, err)
+	}
+}
+
+func TestLoadAndWatchConfigSkipsUnchanged(t *testing.T) {
+	fake := newFakeConfigClient(map[string]string{"n.json": `{"n": 1}`})
+	c := newTestConfigClient(fake)
+	observer := &recordObserver{}
+	c.m.observer = observer
+	var v struct {
+		N int `json:"n"`
+	}
+	f := &ConfigFileSetting{DataId: "n.json", Type: ConfigTypeJson, Value: &v, Watch: true}
+	c.LoadAndWatchConfig([]*ConfigFileSetting{f})
+	// 相同内容的通知不应上报为成功的热更新
+	fake.notify("n.json", `{"n": 1}`)
+	fake.notify("n.json", `{"n": 2}`)
+	if v.N != 2 {
+		t.Fatalf("n = %d", v.N)
+	}
+	if fmt.Sprint(observer.applies) != "[false true]" {
+		t.Errorf("applies = %v", observer.applies)
+	}
+}
+
+func TestLoadAndWatchConfigDebounce(t *testing.T) {
+	fake := newFakeConfigClient(map[string]string{"n.json": `{"n": 0}`})
+	c := newTestConfigClient(fake)
+	observer := &recordObserver{}
+	c.m.observer = observer
+	var v struct {
+		N int `json:"n"`
+	}
+	window := 50 * time.Millisecond
+	f := &ConfigFileSetting{DataId: "n.json", Type: ConfigTypeJson, Value: &v, Watch: true, Debounce: window}
+	c.LoadAndWatchConfig([]*ConfigFileSetting{f})
+	for i := 1; i <= 5; i++ {
+		fake.notify("n.json", fmt.Sprintf(`{"n": %d}`, i))
+	}
+	applies := func() string {
+		observer.mu.Lock()
+		defer observer.mu.Unlock()
+		return fmt.Sprint(observer.applies)
+	}
+	deadline := time.Now().Add(time.Second)
+	for applies() == "[false]" && time.Now().Before(deadline) {
+		time.Sleep(window / 5)
+	}
+	// 等待可能多余的reload
+	time.Sleep(2 * window)
+	// 首次加载与窗口内多次变更合并后的一次热更新
+	if got := applies(); got != "[false true]" {
+		t.Fatalf("applies = %v", got)
+	}
+	if v.N != 5 {
+		t.Errorf("n = %d, want the latest content", v.N)
+	}
+}
+
+func TestGetConfigInvalidParam(t *testing.T) {
+	fake := newFakeConfigClient(map[string]string{})
+	c := newTestConfigClient(fake)
+	var v counterConfig
+	err := c.GetConfig("", ConfigTypeJson, &v)
+	if !errors.Is(err, ErrInvalidParam) || errors.Is(err, ErrServerUnavailable) {
+		t.Errorf("err = %v", err)
+	}
+	if fake.gets != 0 {
+		t.Errorf("sdk called with empty dataId")
+	}
+}
+
+func TestBindClientReload(t *testing.T) {
+	fake := newFakeConfigClient(map[string]string{"counter.json": `{"count": 1}`})
+	c := newTestConfigClient(fake)
+	observer := &recordObserver{}
+	c.m.observer = observer
+	w, err := BindClient[counterConfig](c, "counter.json", ConfigTypeJson)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fake.notify("counter.json", `{"count": 1}`)
+	fake.notify("counter.json", `{"count": `)
+	fake.notify("counter.json", `{"count": 2}`)
+	if w.Get().Count != 2 || w.Version() != 2 {
+		t.Errorf("count = %d version = %d", w.Get().Count, w.Version())
+	}
+	// 首次加载、解码失败与一次成功的热更新 相同内容的通知被忽略
+	if fmt.Sprint(observer.applies) != "[false true true]" {
+		t.Errorf("applies = %v", observer.applies)
+	}
+	if err = w.Close(); err != nil || fake.listening("counter.json") {
+		t.Errorf("close err = %v", err)
+	}
+}
+
+// 所有请求均失败的配置sdk
+type failingConfigClient struct {
+	*fakeConfigClient
+	err error
+}
+
+func (f *failingConfigClient) GetConfig(param vo.ConfigParam) (string, error) {
+	return "", f.err
+}
+
+func (f *failingConfigClient) PublishConfig(param vo.ConfigParam) (bool, error) {
+	return false, f.err
+}
+
+func (f *failingConfigClient) DeleteConfig(param vo.ConfigParam) (bool, error) {
+	return false, f.err
+}
+
+func (f *failingConfigClient) ListenConfig(param vo.ConfigParam) error {
+	return f.err
+}
+
+func TestConfigClientErrors(t *testing.T) {
+	c := newTestConfigClient(&failingConfigClient{fakeConfigClient: newFakeConfigClient(nil), err: errors.New("request timeout")})
+	calls := map[string]func(dataId string) error{
+		"GetConfig": func(dataId string) error {
+			var v counterConfig
+			return c.GetConfig(dataId, ConfigTypeJson, &v)
+		},
+		"PublishConfig": func(dataId string) error {
+			_, err := c.PublishConfig(dataId, ConfigTypeJson, counterConfig{})
+			return err
+		},
+		"DeleteConfig": func(dataId string) error {
+			_, err := c.DeleteConfig(dataId)
+			return err
+		},
+		"WatchConfig": func(dataId string) error {
+			_, err := c.WatchConfig(dataId, func(namespace, group, dataId, data string) {})
+			return err
+		},
+	}
+	for name, call := range calls {
+		if err := call(""); !errors.Is(err, ErrInvalidParam) {
+			t.Errorf("%s with empty dataId: err = %v", name, err)
+		}
+		if err := call("counter.json"); !errors.Is(err, ErrServerUnavailable) {
+			t.Errorf("%s: err = %v", name, err)
+		}
+	}
+
+	if _, err := c.PublishConfig("counter.json", ConfigType("bad"), counterConfig{}); !errors.Is(err, ErrInvalidParam) {
+		t.Errorf("unknown type: err = %v", err)
+	}
+	cas := newTestConfigClient(&casConfigClient{fakeConfigClient: newFakeConfigClient(map[string]string{"counter.json": "{}"})})
+	_, err := cas.PublishConfigCas("counter.json", ConfigTypeJson, counterConfig{}, "stale")
+	if !errors.Is(err, ErrConfigConflict) || errors.Is(err, ErrServerUnavailable) {
+		t.Errorf("cas conflict: err = %v", err)
+	}
+}
+
+func TestWatchConfigFanOut(t *testing.T) {
+	fake := newFakeConfigClient(map[string]string{"app.yaml": "a: 1\n"})
+	c := newTestConfigClient(fake)
+	received := make([]string, 3)
+	var ids []string
+	for i := range received {
+		id, err := c.WatchConfig("app.yaml", func(namespace, group, dataId, data string) {
+			received[i] = data
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, id)
+	}
+	if len(c.watched) != 1 {
+		t.Fatalf("watched = %d, want one sdk listener per dataId", len(c.watched))
+	}
+	fake.notify("app.yaml", "a: 2\n")
+	for i, data := range received {
+		if data != "a: 2\n" {
+			t.Errorf("subscriber %d received %q", i, data)
+		}
+	}
+
+	// 已取消的订阅者不再收到变更
+	if err := c.UnwatchConfig(ids[0]); err != nil {
+		t.Fatal(err)
+	}
+	fake.notify("app.yaml", "a: 3\n")
+	if received[0] != "a: 2\n" || received[1] != "a: 3\n" || received[2] != "a: 3\n" {
+		t.Errorf("received = %q", received)
+	}
+}
+
+func TestUnwatchConfigRefCount(t *testing.T) {
+	fake := newFakeConfigClient(map[string]string{"app.yaml": "a: 1\n"})
+	c := newTestConfigClient(fake)
+	watch := func(namespace, group, dataId, data string) {}
+	first, err := c.WatchConfig("app.yaml", watch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := c.WatchConfig("app.yaml", watch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = c.UnwatchConfig(first); err != nil {
+		t.Fatal(err)
+	}
+	if !fake.listening("app.yaml") {
+		t.Fatal("sdk listener cancelled while a subscriber remains")
+	}
+	if err = c.UnwatchConfig(first); !errors.Is(err, ErrUnknownWatch) {
+		t.Errorf("unwatch twice: %v", err)
+	}
+	if err = c.UnwatchConfig(second); err != nil {
+		t.Fatal(err)
+	}
+	if fake.listening("app.yaml") {
+		t.Error("sdk listener not cancelled after the last unwatch")
+	}
+	if len(c.watched) != 0 || len(c.watchIds) != 0 {
+		t.Errorf("watched = %d, watchIds = %d", len(c.watched), len(c.watchIds))
+	}
+
+	// 全部取消后再次监听重新注册底层监听
+	if _, err = c.WatchConfig("app.yaml", watch); err != nil {
+		t.Fatal(err)
+	}
+	if !fake.listening("app.yaml") {
+		t.Error("sdk listener not registered again")
+	}
+}
@@ -0,0 +1,244 @@
+package nacosstarter
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+const (
+	encryptedPrefix = "ENC("
+	encryptedSuffix = ")"
+	redacted        = "******"
+)
+
+var encryptedPattern = regexp.MustCompile(`ENC\(([A-Za-z0-9+/=_-]+)\)`)
+
+// Decryptor 配置值解密器
+// 配置中形如 ENC(base64...) 的值将在反序列化时被透明解密，cipherText为括号内的内容
+type Decryptor interface {
+	Decrypt(cipherText string) (string, error)
+}
+
+var decryptorLocker sync.RWMutex
+var decryptor Decryptor
+
+// SetDecryptor 设置全局的配置值解密器 传入nil将关闭解密
+func SetDecryptor(d Decryptor) {
+	decryptorLocker.Lock()
+	defer decryptorLocker.Unlock()
+	decryptor = d
+}
+
+func currentDecryptor() Decryptor {
+	decryptorLocker.RLock()
+	defer decryptorLocker.RUnlock()
+	return decryptor
+}
+
+// 解密text格式内容中的 ENC(...) 值 返回解密后的内容及所有明文，用于错误信息脱敏
+func decryptText(content string) (string, []string, error) {
+	d := currentDecryptor()
+	if d == nil || !strings.Contains(content, encryptedPrefix) {
+		return content, nil, nil
+	}
+	var secrets []string
+	var decryptErr error
+	content = encryptedPattern.ReplaceAllStringFunc(content, func(s string) string {
+		plain, err := d.Decrypt(s[len(encryptedPrefix) : len(s)-len(encryptedSuffix)])
+		if err != nil {
+			decryptErr = err
+			return s
+		}
+		secrets = append(secrets, plain)
+		return plain
+	})
+	if decryptErr != nil {
+		return "", nil, fmt.Errorf("decrypt value failed: %w", decryptErr)
+	}
+	return content, secrets, nil
+}
+
+// 解密已反序列化的值中所有形如 ENC(...) 的字符串
+// 直接在反序列化结果上替换，不重新编码原文，数字、日期等其他值保持原样
+func decryptValue(value any, content string) error {
+	d := currentDecryptor()
+	if d == nil || !strings.Contains(content, encryptedPrefix) {
+		return nil
+	}
+	_, err := decryptReflect(d, reflect.ValueOf(value), nil)
+	return err
+}
+
+// 返回v及其子值中是否有字符串被替换
+func decryptReflect(d Decryptor, v reflect.Value, path []pathSegment) (bool, error) {
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() {
+			return false, nil
+		}
+		return decryptReflect(d, v.Elem(), path)
+	case reflect.Interface:
+		if v.IsNil() {
+			return false, nil
+		}
+		// 接口中的值不可寻址 修改副本后写回
+		elem := reflect.New(v.Elem().Type()).Elem()
+		elem.Set(v.Elem())
+		changed, err := decryptReflect(d, elem, path)
+		if changed && v.CanSet() {
+			v.Set(elem)
+		}
+		return changed, err
+	case reflect.Struct:
+		changed := false
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Type().Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			c, err := decryptReflect(d, v.Field(i), append(path[:len(path):len(path)], pathSegment{key: field.Name}))
+			if err != nil {
+				return false, err
+			}
+			changed = changed || c
+		}
+		return changed, nil
+	case reflect.Map:
+		changed := false
+		iter := v.MapRange()
+		for iter.Next() {
+			elem := reflect.New(v.Type().Elem()).Elem()
+			elem.Set(iter.Value())
+			c, err := decryptReflect(d, elem, append(path[:len(path):len(path)], pathSegment{key: fmt.Sprint(iter.Key().Interface())}))
+			if err != nil {
+				return false, err
+			}
+			if c {
+				v.SetMapIndex(iter.Key(), elem)
+				changed = true
+			}
+		}
+		return changed, nil
+	case reflect.Slice, reflect.Array:
+		changed := false
+		for i := 0; i < v.Len(); i++ {
+			c, err := decryptReflect(d, v.Index(i), append(path[:len(path):len(path)], pathSegment{index: i, isIdx: true}))
+			if err != nil {
+				return false, err
+			}
+			changed = changed || c
+		}
+		return changed, nil
+	case reflect.String:
+		s := strings.TrimSpace(v.String())
+		if !strings.HasPrefix(s, encryptedPrefix) || !strings.HasSuffix(s, encryptedSuffix) || !v.CanSet() {
+			return false, nil
+		}
+		plain, err := d.Decrypt(s[len(encryptedPrefix) : len(s)-len(encryptedSuffix)])
+		if err != nil {
+			// 错误信息仅包含键路径 不包含密文与明文
+			return false, fmt.Errorf("decrypt value of %s failed: %w", joinKeyPath(path), err)
+		}
+		v.SetString(plain)
+		return true, nil
+	}
+	return false, nil
+}
+
+// 将错误信息中的明文替换为掩码 避免被日志记录
+func redactError(err error, secrets []string) error {
+	if err == nil || len(secrets) == 0 {
+		return err
+	}
+	msg := err.Error()
+	for _, s := range secrets {
+		if s != "" {
+			msg = strings.ReplaceAll(msg, s, redacted)
+		}
+	}
+	if msg == err.Error() {
+		return err
+	}
+	return errors.New(msg)
+}
+
+// AesGcmDecryptor 基于AES-GCM的解密器
+// 密文格式为 base64(nonce + ciphertext + tag)
+type AesGcmDecryptor struct {
+	aead cipher.AEAD
+}
+
+// NewAesGcmDecryptor 使用16/24/32字节的密钥创建AES-GCM解密器
+func NewAesGcmDecryptor(key []byte) (*AesGcmDecryptor, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &AesGcmDecryptor{aead: aead}, nil
+}
+
+// NewAesGcmDecryptorFromFile 从本地文件读取base64编码的密钥创建AES-GCM解密器
+func NewAesGcmDecryptorFromFile(path string) (*AesGcmDecryptor, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return newAesGcmDecryptorFromBase64(string(content))
+}
+
+// NewAesGcmDecryptorFromEnv 从环境变量读取base64编码的密钥创建AES-GCM解密器
+func NewAesGcmDecryptorFromEnv(name string) (*AesGcmDecryptor, error) {
+	content, ok := os.LookupEnv(name)
+	if !ok {
+		return nil, fmt.Errorf("env %s not set", name)
+	}
+	return newAesGcmDecryptorFromBase64(content)
+}
+
+func newAesGcmDecryptorFromBase64(encoded string) (*AesGcmDecryptor, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil {
+		return nil, fmt.Errorf("bad aes key: %w", err)
+	}
+	return NewAesGcmDecryptor(key)
+}
+
+// Decrypt 解密base64编码的密文
+func (a *AesGcmDecryptor) Decrypt(cipherText string) (string, error) {
+	data, err := base64.StdEncoding.DecodeString(cipherText)
+	if err != nil {
+		return "", err
+	}
+	nonceSize := a.aead.NonceSize()
+	if len(data) < nonceSize {
+		return "", errors.New("cipher text too short")
+	}
+	plain, err := a.aead.Open(nil, data[:nonceSize], data[nonceSize:], nil)
+	if err != nil {
+		return "", err
+	}
+	return string(plain), nil
+}
+
+// Encrypt 加密明文 返回可以直接写入配置的 ENC(...) 形式
+func (a *AesGcmDecryptor) Encrypt(plainText string) (string, error) {
+	nonce := make([]byte, a.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	data := a.aead.Seal(nonce, nonce, []byte(plainText), nil)
+	return encryptedPrefix + base64.StdEncoding.EncodeToString(data) + encryptedSuffix, nil
+}
@@ -0,0 +1,102 @@
+package nacosstarter
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+type decryptConfig struct {
+	Id       int64             `json:"id" yaml:"id"`
+	Ts       string            `json:"ts" yaml:"ts"`
+	Password string            `json:"password" yaml:"password"`
+	Extra    map[string]any    `json:"extra" yaml:"extra"`
+	Tags     []string          `json:"tags" yaml:"tags"`
+	Labels   map[string]string `json:"labels" yaml:"labels"`
+}
+
+func newTestDecryptor(t *testing.T) *AesGcmDecryptor {
+	t.Helper()
+	d, err := NewAesGcmDecryptor([]byte("0123456789abcdef"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	SetDecryptor(d)
+	t.Cleanup(func() { SetDecryptor(nil) })
+	return d
+}
+
+func TestDecryptKeepsOtherValues(t *testing.T) {
+	d := newTestDecryptor(t)
+	secret, err := d.Encrypt("p@ss")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		configType ConfigType
+		content    string
+	}{
+		{ConfigTypeJson, `{"id": 9007199254740993, "ts": "2024-01-01", "password": "` + secret + `",
+			"extra": {"nested": "` + secret + `"}, "tags": ["` + secret + `", "plain"], "labels": {"k": "` + secret + `"}}`},
+		{ConfigTypeYaml, "# comment\nid: 9007199254740993\nts: 2024-01-01\npassword: " + secret +
+			"\nextra:\n  nested: " + secret + "\ntags:\n  - " + secret + "\n  - plain\nlabels:\n  k: " + secret + "\n"},
+	}
+	for _, c := range cases {
+		t.Run(string(c.configType), func(t *testing.T) {
+			var v decryptConfig
+			if err := deserializeConfig(c.content, c.configType, &v); err != nil {
+				t.Fatal(err)
+			}
+			if v.Id != 9007199254740993 {
+				t.Errorf("id = %d", v.Id)
+			}
+			if v.Ts != "2024-01-01" {
+				t.Errorf("ts = %q", v.Ts)
+			}
+			if v.Password != "p@ss" || v.Extra["nested"] != "p@ss" || v.Tags[0] != "p@ss" || v.Labels["k"] != "p@ss" {
+				t.Errorf("not decrypted: %+v", v)
+			}
+			if v.Tags[1] != "plain" {
+				t.Errorf("tags[1] = %q", v.Tags[1])
+			}
+		})
+	}
+}
+
+func TestDecryptTree(t *testing.T) {
+	d := newTestDecryptor(t)
+	secret, _ := d.Encrypt("p@ss")
+	var tree map[string]any
+	if err := deserializeConfig("ts: 2024-01-01\nlist:\n  - "+secret+"\n", ConfigTypeYaml, &tree); err != nil {
+		t.Fatal(err)
+	}
+	if list := tree["list"].([]any); list[0] != "p@ss" {
+		t.Errorf("list[0] = %v", list[0])
+	}
+}
+
+func TestDecryptError(t *testing.T) {
+	newTestDecryptor(t)
+	var v decryptConfig
+	err := deserializeConfig("id: 1\nline: 2\npassword: ENC(bm90LXZhbGlk)\n", ConfigTypeYaml, &v)
+	if !errors.Is(err, ErrDecode) {
+		t.Fatalf("err = %v", err)
+	}
+	if !strings.Contains(err.Error(), "Password") || strings.Contains(err.Error(), "bm90LXZhbGlk") {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func TestDecryptDecodeErrorLine(t *testing.T) {
+	d := newTestDecryptor(t)
+	secret, _ := d.Encrypt("p@ss")
+	var v decryptConfig
+	err := deserializeConfig("password: "+secret+"\n# comment\nid: [\n", ConfigTypeYaml, &v)
+	var de *DecodeError
+	if !errors.As(err, &de) {
+		t.Fatalf("err = %v", err)
+	}
+	if de.Line < 3 {
+		t.Errorf("line = %d, want position in original content", de.Line)
+	}
+}
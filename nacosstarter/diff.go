@@ -0,0 +1,150 @@
+package nacosstarter
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/acexy/golang-toolkit/logger"
+)
+
+// ChangeType 键的变化类型
+type ChangeType string
+
+const (
+	ChangeAdded    ChangeType = "added"
+	ChangeRemoved  ChangeType = "removed"
+	ChangeModified ChangeType = "modified"
+)
+
+// Change 单个键的变化 Key为 a.b[0].c 形式的键路径
+type Change struct {
+	Key      string
+	Type     ChangeType
+	OldValue any
+	NewValue any
+}
+
+// ChangeSet 一次配置变更中所有发生变化的键
+type ChangeSet struct {
+	Group   string
+	DataId  string
+	Changes []Change
+}
+
+// Changed 指定键或其下级键是否发生变化 如 Changed("db") 可以匹配 db.host 与 db.pool[0]
+func (c ChangeSet) Changed(key string) bool {
+	for _, change := range c.Changes {
+		if matchKeyPrefix(change.Key, key) {
+			return true
+		}
+	}
+	return false
+}
+
+// Filter 获取指定键及其下级键的变化
+func (c ChangeSet) Filter(key string) []Change {
+	var result []Change
+	for _, change := range c.Changes {
+		if matchKeyPrefix(change.Key, key) {
+			result = append(result, change)
+		}
+	}
+	return result
+}
+
+func matchKeyPrefix(key, prefix string) bool {
+	if !strings.HasPrefix(key, prefix) {
+		return false
+	}
+	if len(key) == len(prefix) || prefix == "" {
+		return true
+	}
+	return key[len(prefix)] == '.' || key[len(prefix)] == '['
+}
+
+// WatchConfigChanges 监听配置变化并按键路径对比变更内容
+// 仅当存在发生变化的键时才会回调，无法反序列化的内容将被忽略并继续以上一次的内容作为对比基准
+func (c *ConfigClient) WatchConfigChanges(dataId string, configType ConfigType, watch func(changes ChangeSet)) (string, error) {
+	var mu sync.Mutex
+	var previous map[string]any
+	raw, err := c.GetConfigRawContent(dataId)
+	if err != nil {
+		return "", err
+	}
+	if raw != "" {
+		if err = deserializeConfig(raw, configType, &previous); err != nil {
+			return "", withConfigSource(err, c.group, dataId)
+		}
+	}
+	return c.WatchConfig(dataId, func(namespace, group, dataId, data string) {
+		var current map[string]any
+		if data != "" {
+			if err := deserializeConfig(data, configType, &current); err != nil {
+				logger.Logrus().WithError(err).Errorln("cant decode changed config:", dataId, "group:", group)
+				return
+			}
+		}
+		mu.Lock()
+		changes := diffTree(previous, current)
+		previous = current
+		mu.Unlock()
+		if len(changes) > 0 {
+			watch(ChangeSet{Group: group, DataId: dataId, Changes: changes})
+		}
+	})
+}
+
+// 对比两个通用树结构 返回按键路径排列的变化 nil视为空文档
+func diffTree(old, new map[string]any) []Change {
+	var changes []Change
+	diffValue(&changes, nil, old, new)
+	return changes
+}
+
+func diffValue(changes *[]Change, path []pathSegment, old, new any) {
+	oldMap, oldIsMap := old.(map[string]any)
+	newMap, newIsMap := new.(map[string]any)
+	if oldIsMap && newIsMap {
+		keys := make(map[string]struct{}, len(oldMap)+len(newMap))
+		for k := range oldMap {
+			keys[k] = struct{}{}
+		}
+		for k := range newMap {
+			keys[k] = struct{}{}
+		}
+		for _, k := range sortedKeys(keys) {
+			child := append(path[:len(path):len(path)], pathSegment{key: k})
+			o, inOld := oldMap[k]
+			n, inNew := newMap[k]
+			switch {
+			case !inOld:
+				*changes = append(*changes, Change{Key: joinKeyPath(child), Type: ChangeAdded, NewValue: n})
+			case !inNew:
+				*changes = append(*changes, Change{Key: joinKeyPath(child), Type: ChangeRemoved, OldValue: o})
+			default:
+				diffValue(changes, child, o, n)
+			}
+		}
+		return
+	}
+	oldList, oldIsList := old.([]any)
+	newList, newIsList := new.([]any)
+	if oldIsList && newIsList {
+		for i := 0; i < max(len(oldList), len(newList)); i++ {
+			child := append(path[:len(path):len(path)], pathSegment{index: i, isIdx: true})
+			switch {
+			case i >= len(oldList):
+				*changes = append(*changes, Change{Key: joinKeyPath(child), Type: ChangeAdded, NewValue: newList[i]})
+			case i >= len(newList):
+				*changes = append(*changes, Change{Key: joinKeyPath(child), Type: ChangeRemoved, OldValue: oldList[i]})
+			default:
+				diffValue(changes, child, oldList[i], newList[i])
+			}
+		}
+		return
+	}
+	if !reflect.DeepEqual(old, new) {
+		*changes = append(*changes, Change{Key: joinKeyPath(path), Type: ChangeModified, OldValue: old, NewValue: new})
+	}
+}
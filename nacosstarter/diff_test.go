@@ -0,0 +1,94 @@
+package nacosstarter
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiffTree(t *testing.T) {
+	cases := []struct {
+		name string
+		old  map[string]any
+		new  map[string]any
+		want []Change
+	}{
+		{
+			name: "unchanged",
+			old:  map[string]any{"a": 1, "b": map[string]any{"c": []any{"x"}}},
+			new:  map[string]any{"a": 1, "b": map[string]any{"c": []any{"x"}}},
+		},
+		{
+			name: "added",
+			old:  map[string]any{"a": 1},
+			new:  map[string]any{"a": 1, "b": "v"},
+			want: []Change{{Key: "b", Type: ChangeAdded, NewValue: "v"}},
+		},
+		{
+			name: "removed",
+			old:  map[string]any{"a": 1, "b": "v"},
+			new:  map[string]any{"a": 1},
+			want: []Change{{Key: "b", Type: ChangeRemoved, OldValue: "v"}},
+		},
+		{
+			name: "modified",
+			old:  map[string]any{"a": 1},
+			new:  map[string]any{"a": 2},
+			want: []Change{{Key: "a", Type: ChangeModified, OldValue: 1, NewValue: 2}},
+		},
+		{
+			name: "nil documents",
+			old:  nil,
+			new:  map[string]any{"a": 1},
+			want: []Change{{Key: "a", Type: ChangeAdded, NewValue: 1}},
+		},
+		{
+			name: "nested map",
+			old:  map[string]any{"db": map[string]any{"host": "a", "port": 1, "user": "u"}},
+			new:  map[string]any{"db": map[string]any{"host": "b", "port": 1, "pwd": "p"}},
+			want: []Change{
+				{Key: "db.host", Type: ChangeModified, OldValue: "a", NewValue: "b"},
+				{Key: "db.pwd", Type: ChangeAdded, NewValue: "p"},
+				{Key: "db.user", Type: ChangeRemoved, OldValue: "u"},
+			},
+		},
+		{
+			name: "array elements",
+			old:  map[string]any{"list": []any{"a", map[string]any{"n": 1}, "c"}},
+			new:  map[string]any{"list": []any{"a", map[string]any{"n": 2}}},
+			want: []Change{
+				{Key: "list[1].n", Type: ChangeModified, OldValue: 1, NewValue: 2},
+				{Key: "list[2]", Type: ChangeRemoved, OldValue: "c"},
+			},
+		},
+		{
+			name: "array grows",
+			old:  map[string]any{"list": []any{"a"}},
+			new:  map[string]any{"list": []any{"a", "b"}},
+			want: []Change{{Key: "list[1]", Type: ChangeAdded, NewValue: "b"}},
+		},
+		{
+			name: "type changed",
+			old:  map[string]any{"a": map[string]any{"b": 1}},
+			new:  map[string]any{"a": []any{1}},
+			want: []Change{{Key: "a", Type: ChangeModified, OldValue: map[string]any{"b": 1}, NewValue: []any{1}}},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got := diffTree(c.old, c.new)
+			if !reflect.DeepEqual(got, c.want) {
+				t.Errorf("got %#v, want %#v", got, c.want)
+			}
+		})
+	}
+}
+
+func TestChangeSetFilter(t *testing.T) {
+	changes := ChangeSet{Changes: []Change{{Key: "db.host"}, {Key: "db.pool[0]"}, {Key: "dbx"}}}
+	if !changes.Changed("db") || changes.Changed("d") {
+		t.Error("unexpected Changed result")
+	}
+	if got := changes.Filter("db"); len(got) != 2 {
+		t.Errorf("Filter(db) = %v", got)
+	}
+}
@@ -0,0 +1,155 @@
+package nacosstarter
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+var (
+	// ErrConfigNotFound 配置不存在
+	ErrConfigNotFound = errors.New("config not found")
+	// ErrDecode 配置反序列化失败 可以通过errors.As获取*DecodeError查看具体位置
+	ErrDecode = errors.New("decode config failed")
+	// ErrKeyNotFound 配置中不存在指定的键
+	ErrKeyNotFound = errors.New("config key not found")
+	// ErrNotStarted NacosStarter尚未启动或已经停止
+	ErrNotStarted = errors.New("nacos starter not started")
+	// ErrAlreadyStarted NacosStarter已经启动
+	ErrAlreadyStarted = errors.New("nacos starter already started")
+	// ErrInvalidConfig 启动配置或配置项设置无效
+	ErrInvalidConfig = errors.New("invalid nacos config")
+	// ErrInvalidParam 调用参数无效 如dataId或实例列表为空
+	ErrInvalidParam = errors.New("invalid param")
+	// ErrClientDisabled 配置或服务发现功能未启用
+	ErrClientDisabled = errors.New("client disabled")
+	// ErrUnknownWatch 无效的watchId
+	ErrUnknownWatch = errors.New("unknown watch")
+	// ErrDuplicateWatch 重复的监听
+	ErrDuplicateWatch = errors.New("duplicated watch")
+	// ErrUnknownInstance 无效的实例标识
+	ErrUnknownInstance = errors.New("unknown instance")
+	// ErrNoInstance 服务没有可用的实例
+	ErrNoInstance = errors.New("no available instance")
+	// ErrConfigConflict 配置已被其他写入方修改 CAS发布多次失败
+	ErrConfigConflict = errors.New("config conflict")
+	// ErrServerUnavailable 服务端不可用或请求失败
+	ErrServerUnavailable = errors.New("nacos server unavailable")
+)
+
+// DecodeError 配置反序列化错误
+type DecodeError struct {
+	Group  string
+	DataId string
+	Type   ConfigType
+	// 出错的行列号 从1开始，无法确定时为0
+	Line   int
+	Column int
+	Err    error
+}
+
+func (e *DecodeError) Error() string {
+	var b strings.Builder
+	b.WriteString("decode ")
+	b.WriteString(string(e.Type))
+	b.WriteString(" config")
+	if e.DataId != "" {
+		b.WriteString(" " + e.DataId)
+	}
+	if e.Group != "" {
+		b.WriteString(" group " + e.Group)
+	}
+	if e.Line > 0 {
+		b.WriteString(" at line " + strconv.Itoa(e.Line))
+		if e.Column > 0 {
+			b.WriteString(" column " + strconv.Itoa(e.Column))
+		}
+	}
+	b.WriteString(": ")
+	b.WriteString(e.Err.Error())
+	return b.String()
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+func (e *DecodeError) Is(target error) bool {
+	return target == ErrDecode
+}
+
+var errorLinePattern = regexp.MustCompile(`line (\d+)(?:(?::| column | col )(\d+))?`)
+
+// 将反序列化错误包装为DecodeError 并尽可能解析出错位置
+func newDecodeError(content string, configType ConfigType, err error) *DecodeError {
+	var de *DecodeError
+	if errors.As(err, &de) {
+		return de
+	}
+	de = &DecodeError{Type: configType, Err: err}
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	var xmlErr *xml.SyntaxError
+	var tomlErr toml.ParseError
+	switch {
+	case errors.As(err, &syntaxErr):
+		de.Line, de.Column = offsetPosition(content, syntaxErr.Offset)
+	case errors.As(err, &typeErr):
+		de.Line, de.Column = offsetPosition(content, typeErr.Offset)
+	case errors.As(err, &xmlErr):
+		de.Line = xmlErr.Line
+	case errors.As(err, &tomlErr):
+		de.Line, de.Column = tomlErr.Position.Line, tomlErr.Position.Col
+	default:
+		if m := errorLinePattern.FindStringSubmatch(err.Error()); m != nil {
+			de.Line, _ = strconv.Atoi(m[1])
+			de.Column, _ = strconv.Atoi(m[2])
+		}
+	}
+	return de
+}
+
+// 根据字节偏移量计算行列号
+func offsetPosition(content string, offset int64) (int, int) {
+	if offset <= 0 {
+		return 0, 0
+	}
+	if offset > int64(len(content)) {
+		offset = int64(len(content))
+	}
+	before := content[:offset]
+	line := strings.Count(before, "\n") + 1
+	column := int(offset) - strings.LastIndex(before, "\n") - 1
+	return line, column
+}
+
+// 为错误中的DecodeError补充配置来源
+func withConfigSource(err error, group, dataId string) error {
+	var de *DecodeError
+	if errors.As(err, &de) {
+		de.Group, de.DataId = group, dataId
+	}
+	return err
+}
+
+// 参数在调用sdk前完成校验 sdk返回的错误均视为服务端请求失败
+func serverError(err error) error {
+	if err == nil {
+		return nil
+	}
+	return fmt.Errorf("%w: %w", ErrServerUnavailable, err)
+}
+
+func invalidParam(name string) error {
+	return fmt.Errorf("%w: empty %s", ErrInvalidParam, name)
+}
+
+func configNotFound(group, dataId string) error {
+	return fmt.Errorf("%w: %s group %s", ErrConfigNotFound, dataId, group)
+}
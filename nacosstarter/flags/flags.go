@@ -0,0 +1,232 @@
+// Package flags 基于Nacos配置的功能开关
+//
+// 开关定义保存在一个dataId中，配置变化后实时生效，yaml格式示例:
+//
+//	flags:
+//	  new-checkout:
+//	    enabled: true
+//	    # 按userId分桶，30%的用户开启
+//	    rollout: 30
+//	    stickiness: userId
+//	    # 命中白名单直接开启，不参与分桶
+//	    allow:
+//	      tenant: [t1, t2]
+//	    # 命中黑名单直接关闭
+//	    deny:
+//	      userId: ["10086"]
+//	    variants:
+//	      - name: blue
+//	        weight: 50
+//	      - name: green
+//	        weight: 50
+package flags
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"slices"
+
+	"github.com/golang-acexy/starter-nacos/nacosstarter"
+)
+
+// 默认参与分桶的属性
+const defaultStickiness = "userId"
+
+// 分桶精度 万分之一
+const bucketSize = 10000
+
+// Attributes 判断开关时使用的用户、租户等属性
+type Attributes map[string]string
+
+// Definition 单个开关的定义
+type Definition struct {
+	// 总开关 关闭时其他规则均不生效
+	Enabled bool `json:"enabled" yaml:"enabled" toml:"enabled"`
+	// 百分比灰度 取值0-100，支持两位小数，未设置时对所有人开启
+	Rollout *float64 `json:"rollout" yaml:"rollout" toml:"rollout"`
+	// 参与分桶的属性名 默认userId，缺少该属性时不会命中灰度
+	Stickiness string `json:"stickiness" yaml:"stickiness" toml:"stickiness"`
+	// 属性白名单 任意属性命中即开启
+	Allow map[string][]string `json:"allow" yaml:"allow" toml:"allow"`
+	// 属性黑名单 优先于白名单
+	Deny map[string][]string `json:"deny" yaml:"deny" toml:"deny"`
+	// 开关开启时按权重分配的变体
+	Variants []Variant `json:"variants" yaml:"variants" toml:"variants"`
+}
+
+// Variant 开关变体
+type Variant struct {
+	Name   string `json:"name" yaml:"name" toml:"name"`
+	Weight int    `json:"weight" yaml:"weight" toml:"weight"`
+}
+
+// 开关配置文件的结构
+type document struct {
+	Flags map[string]Definition `json:"flags" yaml:"flags" toml:"flags"`
+}
+
+// Validate 校验失败的开关配置不会被应用
+func (d *document) Validate() error {
+	for name, def := range d.Flags {
+		if def.Rollout != nil && (*def.Rollout < 0 || *def.Rollout > 100) {
+			return fmt.Errorf("flag %s rollout must be between 0 and 100", name)
+		}
+		for _, v := range def.Variants {
+			if v.Name == "" {
+				return fmt.Errorf("flag %s has variant without name", name)
+			}
+			if v.Weight < 0 {
+				return fmt.Errorf("flag %s variant %s has negative weight", name, v.Name)
+			}
+		}
+	}
+	return nil
+}
+
+// Flags 功能开关集合
+type Flags struct {
+	watched *nacosstarter.Watched[document]
+}
+
+// New 从指定配置客户端加载开关定义并监听变化
+func New(client *nacosstarter.ConfigClient, dataId string, configType nacosstarter.ConfigType) (*Flags, error) {
+	watched, err := nacosstarter.BindClient[document](client, dataId, configType)
+	if err != nil {
+		return nil, err
+	}
+	return &Flags{watched: watched}, nil
+}
+
+// Load 使用默认starter加载开关定义 配置格式根据dataId的扩展名推断
+func Load(group, dataId string) (*Flags, error) {
+	client, err := nacosstarter.GetConfigClient(group)
+	if err != nil {
+		return nil, err
+	}
+	return New(client, dataId, nacosstarter.ConfigTypeOf(dataId))
+}
+
+// Close 停止监听开关变化 已加载的定义保持不变
+func (f *Flags) Close() error {
+	return f.watched.Close()
+}
+
+// Definition 获取开关定义
+func (f *Flags) Definition(name string) (Definition, bool) {
+	def, ok := f.watched.Get().Flags[name]
+	return def, ok
+}
+
+// Enabled 判断开关对指定属性是否开启 未定义的开关视为关闭
+// attrs与通过WithAttributes放入ctx的属性合并，attrs优先
+func (f *Flags) Enabled(ctx context.Context, name string, attrs Attributes) bool {
+	def, ok := f.Definition(name)
+	if !ok {
+		return false
+	}
+	return def.enabled(name, mergeAttributes(ctx, attrs))
+}
+
+// Variant 获取开关对指定属性命中的变体 开关关闭或未定义变体时返回空字符串
+// 同一属性在权重不变时总是命中同一个变体
+func (f *Flags) Variant(ctx context.Context, name string, attrs Attributes) string {
+	def, ok := f.Definition(name)
+	if !ok {
+		return ""
+	}
+	attrs = mergeAttributes(ctx, attrs)
+	if !def.enabled(name, attrs) {
+		return ""
+	}
+	return def.variant(name, attrs)
+}
+
+func (d Definition) stickiness() string {
+	if d.Stickiness == "" {
+		return defaultStickiness
+	}
+	return d.Stickiness
+}
+
+func (d Definition) enabled(name string, attrs Attributes) bool {
+	if !d.Enabled || matchAttributes(d.Deny, attrs) {
+		return false
+	}
+	if matchAttributes(d.Allow, attrs) {
+		return true
+	}
+	if d.Rollout == nil {
+		return true
+	}
+	value, ok := attrs[d.stickiness()]
+	if !ok {
+		return false
+	}
+	// 取整后比较 避免0.07等小数换算出的桶数多出一个
+	threshold := math.Round(*d.Rollout * bucketSize / 100)
+	return float64(bucket(name, value, bucketSize)) < threshold
+}
+
+func (d Definition) variant(name string, attrs Attributes) string {
+	total := 0
+	for _, v := range d.Variants {
+		total += v.Weight
+	}
+	if total == 0 {
+		return ""
+	}
+	// 与灰度使用不同的分桶 避免灰度比例影响变体分布
+	n := bucket(name+"/variant", attrs[d.stickiness()], uint32(total))
+	for _, v := range d.Variants {
+		if n < uint32(v.Weight) {
+			return v.Name
+		}
+		n -= uint32(v.Weight)
+	}
+	return ""
+}
+
+func matchAttributes(rules map[string][]string, attrs Attributes) bool {
+	for key, values := range rules {
+		if value, ok := attrs[key]; ok && slices.Contains(values, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// 根据开关名称与属性值计算稳定的分桶 不同进程、不同实例的结果一致
+func bucket(name, value string, size uint32) uint32 {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(name))
+	_, _ = h.Write([]byte{0})
+	_, _ = h.Write([]byte(value))
+	return h.Sum32() % size
+}
+
+type attributesKey struct{}
+
+// WithAttributes 将属性放入ctx 与已有属性合并，新属性优先
+func WithAttributes(ctx context.Context, attrs Attributes) context.Context {
+	return context.WithValue(ctx, attributesKey{}, mergeAttributes(ctx, attrs))
+}
+
+func mergeAttributes(ctx context.Context, attrs Attributes) Attributes {
+	if ctx == nil {
+		return attrs
+	}
+	existing, _ := ctx.Value(attributesKey{}).(Attributes)
+	if len(existing) == 0 {
+		return attrs
+	}
+	merged := make(Attributes, len(existing)+len(attrs))
+	for k, v := range existing {
+		merged[k] = v
+	}
+	for k, v := range attrs {
+		merged[k] = v
+	}
+	return merged
+}
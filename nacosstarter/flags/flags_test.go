@@ -0,0 +1,193 @@
+package flags
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"testing"
+)
+
+func rollout(v float64) *float64 {
+	return &v
+}
+
+func TestBucketDeterministic(t *testing.T) {
+	// 分桶结果需要在不同进程、不同版本间保持一致
+	cases := []struct {
+		name, value string
+		size, want  uint32
+	}{
+		{"new-checkout", "10086", bucketSize, 413},
+		{"new-checkout/variant", "10086", 100, 19},
+	}
+	for _, c := range cases {
+		if got := bucket(c.name, c.value, c.size); got != c.want {
+			t.Errorf("bucket(%q, %q) = %d, want %d", c.name, c.value, got, c.want)
+		}
+	}
+	// 不同开关的分桶相互独立
+	same := 0
+	for i := 0; i < 1000; i++ {
+		value := strconv.Itoa(i)
+		if bucket("a", value, bucketSize) == bucket("b", value, bucketSize) {
+			same++
+		}
+	}
+	if same > 10 {
+		t.Errorf("%d of 1000 values share buckets across flags", same)
+	}
+}
+
+func TestRolloutBoundaries(t *testing.T) {
+	cases := []struct {
+		rollout *float64
+		// 期望开启的比例
+		want float64
+	}{
+		{nil, 1},
+		{rollout(0), 0},
+		{rollout(100), 1},
+		{rollout(30), 0.3},
+		{rollout(0.5), 0.005},
+	}
+	const users = 20000
+	for _, c := range cases {
+		t.Run(fmt.Sprint(c.want), func(t *testing.T) {
+			def := Definition{Enabled: true, Rollout: c.rollout}
+			enabled := 0
+			for i := 0; i < users; i++ {
+				if def.enabled("rollout", Attributes{"userId": strconv.Itoa(i)}) {
+					enabled++
+				}
+			}
+			if got := float64(enabled) / users; math.Abs(got-c.want) > 0.01 {
+				t.Errorf("enabled ratio = %.4f, want %.4f", got, c.want)
+			}
+		})
+	}
+	// 桶号为b的用户在灰度为b/100时关闭，为(b+1)/100时开启
+	for i := 0; i < 2000; i++ {
+		value := strconv.Itoa(i)
+		b := bucket("edge", value, bucketSize)
+		below, _ := strconv.ParseFloat(fmt.Sprintf("%d.%02d", b/100, b%100), 64)
+		above, _ := strconv.ParseFloat(fmt.Sprintf("%d.%02d", (b+1)/100, (b+1)%100), 64)
+		attrs := Attributes{"userId": value}
+		if (Definition{Enabled: true, Rollout: &below}).enabled("edge", attrs) {
+			t.Fatalf("bucket %d enabled at rollout %v", b, below)
+		}
+		if !(Definition{Enabled: true, Rollout: &above}).enabled("edge", attrs) {
+			t.Fatalf("bucket %d disabled at rollout %v", b, above)
+		}
+	}
+}
+
+func TestEnabledRules(t *testing.T) {
+	allow := map[string][]string{"tenant": {"t1"}}
+	deny := map[string][]string{"userId": {"10086"}}
+	cases := []struct {
+		name  string
+		def   Definition
+		attrs Attributes
+		want  bool
+	}{
+		{"disabled", Definition{Allow: allow}, Attributes{"tenant": "t1"}, false},
+		{"enabled", Definition{Enabled: true}, nil, true},
+		{"allow skips rollout", Definition{Enabled: true, Rollout: rollout(0), Allow: allow}, Attributes{"tenant": "t1"}, true},
+		{"allow miss", Definition{Enabled: true, Rollout: rollout(0), Allow: allow}, Attributes{"tenant": "t2"}, false},
+		{"deny", Definition{Enabled: true, Deny: deny}, Attributes{"userId": "10086"}, false},
+		{"deny over allow", Definition{Enabled: true, Allow: allow, Deny: deny}, Attributes{"tenant": "t1", "userId": "10086"}, false},
+		{"missing stickiness", Definition{Enabled: true, Rollout: rollout(100)}, Attributes{"tenant": "t1"}, false},
+		{"custom stickiness", Definition{Enabled: true, Rollout: rollout(100), Stickiness: "tenant"}, Attributes{"tenant": "t1"}, true},
+	}
+	for _, c := range cases {
+		if got := c.def.enabled("rules", c.attrs); got != c.want {
+			t.Errorf("%s: enabled = %v, want %v", c.name, got, c.want)
+		}
+	}
+}
+
+func TestVariantWeights(t *testing.T) {
+	cases := []struct {
+		name     string
+		variants []Variant
+		// 期望各变体命中的比例 空字符串表示未命中变体
+		want map[string]float64
+	}{
+		{"none", nil, map[string]float64{"": 1}},
+		{"zero weights", []Variant{{Name: "a"}, {Name: "b"}}, map[string]float64{"": 1}},
+		{"single", []Variant{{Name: "a", Weight: 100}, {Name: "b"}}, map[string]float64{"a": 1}},
+		{"even", []Variant{{Name: "a", Weight: 50}, {Name: "b", Weight: 50}}, map[string]float64{"a": 0.5, "b": 0.5}},
+		{"weighted", []Variant{{Name: "a", Weight: 1}, {Name: "b", Weight: 3}}, map[string]float64{"a": 0.25, "b": 0.75}},
+	}
+	const users = 20000
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			def := Definition{Enabled: true, Variants: c.variants}
+			counts := make(map[string]int)
+			for i := 0; i < users; i++ {
+				attrs := Attributes{"userId": strconv.Itoa(i)}
+				v := def.variant("variants", attrs)
+				if again := def.variant("variants", attrs); again != v {
+					t.Fatalf("user %d got %q then %q", i, v, again)
+				}
+				counts[v]++
+			}
+			for name, count := range counts {
+				if _, ok := c.want[name]; !ok {
+					t.Errorf("unexpected variant %q", name)
+					continue
+				}
+				if got := float64(count) / users; math.Abs(got-c.want[name]) > 0.02 {
+					t.Errorf("variant %q ratio = %.4f, want %.2f", name, got, c.want[name])
+				}
+			}
+		})
+	}
+}
+
+func TestVariantIndependentOfRollout(t *testing.T) {
+	variants := []Variant{{Name: "a", Weight: 50}, {Name: "b", Weight: 50}}
+	def := Definition{Enabled: true, Rollout: rollout(50), Variants: variants}
+	counts := make(map[string]int)
+	for i := 0; i < 20000; i++ {
+		attrs := Attributes{"userId": strconv.Itoa(i)}
+		if def.enabled("split", attrs) {
+			counts[def.variant("split", attrs)]++
+		}
+	}
+	// 灰度命中的用户中变体仍按权重分布
+	total := counts["a"] + counts["b"]
+	if ratio := float64(counts["a"]) / float64(total); math.Abs(ratio-0.5) > 0.03 {
+		t.Errorf("variant a ratio among rollout = %.4f", ratio)
+	}
+}
+
+func TestDocumentValidate(t *testing.T) {
+	cases := []struct {
+		name  string
+		def   Definition
+		valid bool
+	}{
+		{"ok", Definition{Rollout: rollout(100), Variants: []Variant{{Name: "a", Weight: 1}}}, true},
+		{"rollout above", Definition{Rollout: rollout(100.01)}, false},
+		{"rollout below", Definition{Rollout: rollout(-1)}, false},
+		{"unnamed variant", Definition{Variants: []Variant{{Weight: 1}}}, false},
+		{"negative weight", Definition{Variants: []Variant{{Name: "a", Weight: -1}}}, false},
+	}
+	for _, c := range cases {
+		d := &document{Flags: map[string]Definition{c.name: c.def}}
+		if err := d.Validate(); (err == nil) != c.valid {
+			t.Errorf("%s: err = %v", c.name, err)
+		}
+	}
+}
+
+func TestMergeAttributes(t *testing.T) {
+	ctx := WithAttributes(context.Background(), Attributes{"userId": "1", "tenant": "t1"})
+	ctx = WithAttributes(ctx, Attributes{"region": "cn"})
+	attrs := mergeAttributes(ctx, Attributes{"userId": "2"})
+	if attrs["userId"] != "2" || attrs["tenant"] != "t1" || attrs["region"] != "cn" {
+		t.Errorf("attrs = %v", attrs)
+	}
+}
@@ -0,0 +1,202 @@
+package nacosstarter
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/acexy/golang-toolkit/crypto/hashing"
+	"github.com/acexy/golang-toolkit/logger"
+)
+
+const defaultGroupWatchInterval = 30 * time.Second
+
+// GroupEventType 分组内配置的变化类型
+type GroupEventType string
+
+const (
+	GroupConfigCreated GroupEventType = "created"
+	GroupConfigUpdated GroupEventType = "updated"
+	GroupConfigDeleted GroupEventType = "deleted"
+)
+
+// GroupEvent 分组内配置的变化事件 Deleted事件的Content为空
+type GroupEvent struct {
+	Type    GroupEventType
+	Group   string
+	DataId  string
+	Content string
+}
+
+// 分组监听 known记录当前已监听的dataId
+type groupWatch struct {
+	client  *ConfigClient
+	pattern string
+	handler func(event GroupEvent)
+	stop    chan struct{}
+
+	mu      sync.Mutex
+	stopped bool
+	known   map[string]*groupMember
+}
+
+type groupMember struct {
+	watchId string
+	md5     string
+}
+
+// WatchGroup 监听当前分组中dataId匹配pattern的所有配置
+// pattern支持 * 通配符，为空时匹配分组内所有配置
+// 分组将按NacosConfig.GroupWatchInterval定期扫描，新出现的配置会自动开始监听并产生Created事件，
+// 已监听的配置内容变化时产生Updated事件，被删除时产生Deleted事件并取消监听
+// 启动时已存在的配置同样会在返回前产生Created事件，所有事件按顺序串行回调
+func (c *ConfigClient) WatchGroup(pattern string, handler func(event GroupEvent)) (string, error) {
+	if pattern == "" {
+		pattern = "*"
+	}
+	summaries, err := c.scanGroup(pattern)
+	if err != nil {
+		return "", err
+	}
+	g := &groupWatch{client: c, pattern: pattern, handler: handler, stop: make(chan struct{}), known: make(map[string]*groupMember)}
+	g.sync(summaries)
+
+	c.mu.Lock()
+	if c.groupWatches == nil {
+		c.groupWatches = make(map[string]*groupWatch)
+	}
+	c.watchSeq++
+	watchId := "group:" + c.group + ":" + strconv.FormatUint(c.watchSeq, 10)
+	c.groupWatches[watchId] = g
+	c.mu.Unlock()
+
+	go g.run(c.m.groupWatchInterval, c.m.done)
+	return watchId, nil
+}
+
+// UnwatchGroup 取消分组监听 并取消其下所有配置的监听
+func (c *ConfigClient) UnwatchGroup(watchId string) error {
+	c.mu.Lock()
+	g, ok := c.groupWatches[watchId]
+	delete(c.groupWatches, watchId)
+	c.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("%w %s", ErrUnknownWatch, watchId)
+	}
+	// 由扫描协程负责清理 避免在事件回调中调用时产生死锁
+	close(g.stop)
+	return nil
+}
+
+func (c *ConfigClient) scanGroup(pattern string) (map[string]ConfigSummary, error) {
+	summaries := make(map[string]ConfigSummary)
+	for summary, err := range c.SearchAll(ConfigQuery{DataId: pattern}) {
+		if err != nil {
+			return nil, err
+		}
+		summaries[summary.DataId] = summary
+	}
+	return summaries, nil
+}
+
+func (g *groupWatch) run(interval time.Duration, done <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-g.stop:
+			g.close()
+			return
+		case <-ticker.C:
+			summaries, err := g.client.scanGroup(g.pattern)
+			if err != nil {
+				// 扫描失败时不能据此判断配置已被删除
+				logger.Logrus().WithError(err).Warnln("cant scan config group:", g.client.group, "pattern:", g.pattern)
+				continue
+			}
+			g.sync(summaries)
+		}
+	}
+}
+
+// 根据扫描结果开始监听新的配置 取消已删除配置的监听
+func (g *groupWatch) sync(summaries map[string]ConfigSummary) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.stopped {
+		return
+	}
+	for _, dataId := range sortedKeys(summaries) {
+		if _, ok := g.known[dataId]; ok {
+			continue
+		}
+		g.add(dataId)
+	}
+	for _, dataId := range sortedKeys(g.known) {
+		if _, ok := summaries[dataId]; !ok {
+			g.remove(dataId)
+		}
+	}
+}
+
+func (g *groupWatch) add(dataId string) {
+	c := g.client
+	watchId, err := c.WatchConfig(dataId, func(namespace, group, dataId, data string) {
+		g.changed(dataId, data)
+	})
+	if err != nil {
+		logger.Logrus().WithError(err).Errorln("cant watch config:", dataId, "group:", c.group)
+		return
+	}
+	content, err := c.GetConfigRawContent(dataId)
+	if err != nil || content == "" {
+		// 下次扫描时重试
+		_ = c.UnwatchConfig(watchId)
+		return
+	}
+	g.known[dataId] = &groupMember{watchId: watchId, md5: hashing.Md5Hex(content)}
+	g.handler(GroupEvent{Type: GroupConfigCreated, Group: c.group, DataId: dataId, Content: content})
+}
+
+func (g *groupWatch) remove(dataId string) {
+	member := g.known[dataId]
+	delete(g.known, dataId)
+	if err := g.client.UnwatchConfig(member.watchId); err != nil {
+		logger.Logrus().WithError(err).Warnln("cant unwatch config:", dataId, "group:", g.client.group)
+	}
+	g.handler(GroupEvent{Type: GroupConfigDeleted, Group: g.client.group, DataId: dataId})
+}
+
+func (g *groupWatch) changed(dataId, data string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	member, ok := g.known[dataId]
+	if g.stopped || !ok {
+		return
+	}
+	if data == "" {
+		g.remove(dataId)
+		return
+	}
+	md5 := hashing.Md5Hex(data)
+	if md5 == member.md5 {
+		return
+	}
+	member.md5 = md5
+	g.handler(GroupEvent{Type: GroupConfigUpdated, Group: g.client.group, DataId: dataId, Content: data})
+}
+
+func (g *groupWatch) close() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.stopped = true
+	for dataId, member := range g.known {
+		if err := g.client.UnwatchConfig(member.watchId); err != nil {
+			logger.Logrus().WithError(err).Warnln("cant unwatch config:", dataId, "group:", g.client.group)
+		}
+	}
+	g.known = nil
+}
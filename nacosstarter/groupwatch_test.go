@@ -0,0 +1,90 @@
+package nacosstarter
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/nacos-group/nacos-sdk-go/v2/model"
+	"github.com/nacos-group/nacos-sdk-go/v2/vo"
+)
+
+// 搜索结果为当前全部非空配置
+type groupConfigClient struct {
+	*fakeConfigClient
+}
+
+func (f *groupConfigClient) SearchConfig(param vo.SearchConfigParam) (*model.ConfigPage, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	page := &model.ConfigPage{PageNumber: param.PageNo, PagesAvailable: 1}
+	for _, dataId := range sortedKeys(f.contents) {
+		if f.contents[dataId] != "" {
+			page.PageItems = append(page.PageItems, model.ConfigItem{DataId: dataId, Group: "DEFAULT_GROUP", Content: f.contents[dataId]})
+		}
+	}
+	page.TotalCount = len(page.PageItems)
+	return page, nil
+}
+
+func (f *groupConfigClient) set(dataId, content string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.contents[dataId] = content
+}
+
+func TestGroupWatchSync(t *testing.T) {
+	fake := &groupConfigClient{newFakeConfigClient(map[string]string{"a.yaml": "a: 1\n", "b.yaml": "b: 1\n"})}
+	c := newTestConfigClient(fake)
+	defer close(c.m.done)
+	// 由测试直接触发扫描
+	c.m.groupWatchInterval = time.Hour
+	var events []GroupEvent
+	watchId, err := c.WatchGroup("*", func(event GroupEvent) {
+		events = append(events, event)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := c.groupWatches[watchId]
+	scan := func() []GroupEvent {
+		events = nil
+		summaries, err := c.scanGroup(g.pattern)
+		if err != nil {
+			t.Fatal(err)
+		}
+		g.sync(summaries)
+		return events
+	}
+	want := []GroupEvent{
+		{Type: GroupConfigCreated, Group: "DEFAULT_GROUP", DataId: "a.yaml", Content: "a: 1\n"},
+		{Type: GroupConfigCreated, Group: "DEFAULT_GROUP", DataId: "b.yaml", Content: "b: 1\n"},
+	}
+	if !reflect.DeepEqual(events, want) {
+		t.Fatalf("initial events = %+v", events)
+	}
+	if got := scan(); len(got) != 0 {
+		t.Errorf("unchanged group produced events %+v", got)
+	}
+
+	fake.set("c.yaml", "c: 1\n")
+	fake.set("a.yaml", "")
+	want = []GroupEvent{
+		{Type: GroupConfigCreated, Group: "DEFAULT_GROUP", DataId: "c.yaml", Content: "c: 1\n"},
+		{Type: GroupConfigDeleted, Group: "DEFAULT_GROUP", DataId: "a.yaml"},
+	}
+	if got := scan(); !reflect.DeepEqual(got, want) {
+		t.Errorf("events = %+v, want %+v", got, want)
+	}
+	if fake.listening("a.yaml") || !fake.listening("b.yaml") || !fake.listening("c.yaml") {
+		t.Error("listeners not synced with the group")
+	}
+
+	// 已监听的配置变化产生Updated事件
+	events = nil
+	fake.notify("b.yaml", "b: 2\n")
+	want = []GroupEvent{{Type: GroupConfigUpdated, Group: "DEFAULT_GROUP", DataId: "b.yaml", Content: "b: 2\n"}}
+	if !reflect.DeepEqual(events, want) {
+		t.Errorf("events = %+v, want %+v", events, want)
+	}
+}
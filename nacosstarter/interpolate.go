@@ -0,0 +1,229 @@
+package nacosstarter
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// 占位符解析上下文
+type interpolator struct {
+	root   *yaml.Node
+	strict bool
+	// 正在解析中的键 用于检测循环引用
+	resolving []string
+}
+
+// Interpolate 解析配置内容中的占位符
+//
+// 支持的形式:
+//   - ${NAME} 依次从环境变量、当前文档中同名键路径(如 ${server.port}、${list[0].name})查找
+//   - ${NAME:default} 未找到时使用默认值，默认值中可以继续嵌套占位符
+//
+// 结构化格式先解析文档，仅替换其中的字符串值后再按原格式重新编码，替换结果会按格式转义，不会改变文档结构;
+// yaml中未加引号的值替换后重新推断类型，如 port: ${PORT} 可以绑定到整数字段;
+// json与toml中仅由一个占位符组成的字符串值同样重新推断类型，如 "port": "${PORT}"，替换结果为数字或布尔值时不能再绑定到字符串字段。
+// toml中未加引号的占位符值(如 port = ${PORT})按字符串处理后再替换。text格式直接替换原文
+// 引用文档中的键时，该键的值若包含占位符将被递归解析，出现循环引用时返回错误
+// strict为true时存在无法解析的占位符将返回错误，否则保留占位符原文
+func Interpolate(content string, configType ConfigType, strict bool) (string, error) {
+	if !strings.Contains(content, "${") {
+		return content, nil
+	}
+	i := &interpolator{strict: strict}
+	if configType == ConfigTypeText {
+		return i.expand(content)
+	}
+	retype := configType == ConfigTypeJson || configType == ConfigTypeToml
+	if configType == ConfigTypeToml {
+		content = tomlPlaceholderPattern.ReplaceAllString(content, "${1}'${2}'")
+	}
+	root, err := documentNode(content, configType)
+	if err != nil || root == nil {
+		return content, err
+	}
+	i.root = root
+	// 先解析全部值再替换 引用的键始终使用文档中的原始值
+	type replacement struct {
+		node   *yaml.Node
+		value  string
+		retype bool
+	}
+	var replacements []replacement
+	err = walkValues(root, func(node *yaml.Node) error {
+		if !strings.Contains(node.Value, "${") {
+			return nil
+		}
+		v, err := i.expand(node.Value)
+		if err == nil && v != node.Value {
+			replacements = append(replacements, replacement{node: node, value: v, retype: retype && isPlaceholder(node.Value)})
+		}
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+	if len(replacements) == 0 {
+		return content, nil
+	}
+	for _, r := range replacements {
+		r.node.Value = r.value
+		if r.retype {
+			r.node.Style = 0
+		}
+		if r.node.Style&(yaml.DoubleQuotedStyle|yaml.SingleQuotedStyle|yaml.LiteralStyle|yaml.FoldedStyle|yaml.TaggedStyle) == 0 {
+			r.node.Tag = ""
+		}
+	}
+	return encodeNode(root, configType)
+}
+
+// toml中未加引号的整行占位符值 字面量字符串不处理转义，占位符原文保持不变
+var tomlPlaceholderPattern = regexp.MustCompile(`(?m)^(\s*[^#\s\[][^=\n]*=[ \t]*)(\$\{[^'\n]*\})[ \t]*$`)
+
+// 值是否仅由一个占位符组成
+func isPlaceholder(s string) bool {
+	return strings.HasPrefix(s, "${") && matchingBrace(s, 2) == len(s)-1
+}
+
+// 遍历所有标量值 不包含对象的键
+func walkValues(node *yaml.Node, fn func(node *yaml.Node) error) error {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		return fn(node)
+	case yaml.MappingNode:
+		for j := 1; j < len(node.Content); j += 2 {
+			if err := walkValues(node.Content[j], fn); err != nil {
+				return err
+			}
+		}
+	case yaml.SequenceNode:
+		for _, child := range node.Content {
+			if err := walkValues(child, fn); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (i *interpolator) expand(s string) (string, error) {
+	var b strings.Builder
+	for {
+		start := strings.Index(s, "${")
+		if start < 0 {
+			b.WriteString(s)
+			return b.String(), nil
+		}
+		end := matchingBrace(s, start+2)
+		if end < 0 {
+			if i.strict {
+				return "", fmt.Errorf("unclosed placeholder %s", s[start:])
+			}
+			b.WriteString(s)
+			return b.String(), nil
+		}
+		b.WriteString(s[:start])
+		v, err := i.resolve(s[start+2 : end])
+		if err != nil {
+			return "", err
+		}
+		b.WriteString(v)
+		s = s[end+1:]
+	}
+}
+
+// 查找与起始位置匹配的右括号 支持嵌套的占位符
+func matchingBrace(s string, from int) int {
+	depth := 1
+	for j := from; j < len(s); j++ {
+		switch {
+		case s[j] == '{' && j > 0 && s[j-1] == '$':
+			depth++
+		case s[j] == '}':
+			depth--
+			if depth == 0 {
+				return j
+			}
+		}
+	}
+	return -1
+}
+
+func (i *interpolator) resolve(expr string) (string, error) {
+	name, def, hasDef := strings.Cut(expr, ":")
+	name = strings.TrimSpace(name)
+	if v, ok := os.LookupEnv(name); ok {
+		return v, nil
+	}
+	if v, ok, err := i.lookupKey(name); err != nil || ok {
+		return v, err
+	}
+	if hasDef {
+		return i.expand(def)
+	}
+	if i.strict {
+		return "", fmt.Errorf("unresolved placeholder ${%s}", expr)
+	}
+	return "${" + expr + "}", nil
+}
+
+func (i *interpolator) lookupKey(name string) (string, bool, error) {
+	if i.root == nil {
+		return "", false, nil
+	}
+	segments, err := parseKeyPath(name)
+	if err != nil {
+		return "", false, nil
+	}
+	node, ok := lookupNode(i.root, segments)
+	if !ok {
+		return "", false, nil
+	}
+	if node.Kind != yaml.ScalarNode {
+		return "", false, fmt.Errorf("placeholder ${%s} refers to a non scalar value", name)
+	}
+	for _, r := range i.resolving {
+		if r == name {
+			return "", false, fmt.Errorf("circular placeholder reference %s -> %s", strings.Join(i.resolving, " -> "), name)
+		}
+	}
+	if node.ShortTag() == "!!null" {
+		return "", true, nil
+	}
+	i.resolving = append(i.resolving, name)
+	defer func() { i.resolving = i.resolving[:len(i.resolving)-1] }()
+	resolved, err := i.expand(node.Value)
+	return resolved, true, err
+}
+
+// 在节点树中按键路径查找节点
+func lookupNode(node *yaml.Node, segments []pathSegment) (*yaml.Node, bool) {
+	current := node
+	for _, s := range segments {
+		switch {
+		case s.isIdx && current.Kind == yaml.SequenceNode:
+			if s.index >= len(current.Content) {
+				return nil, false
+			}
+			current = current.Content[s.index]
+		case !s.isIdx && current.Kind == yaml.MappingNode:
+			found := false
+			for j := 0; j+1 < len(current.Content); j += 2 {
+				if current.Content[j].Value == s.key {
+					current, found = current.Content[j+1], true
+					break
+				}
+			}
+			if !found {
+				return nil, false
+			}
+		default:
+			return nil, false
+		}
+	}
+	return current, true
+}
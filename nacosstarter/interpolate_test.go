@@ -0,0 +1,181 @@
+package nacosstarter
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestInterpolate(t *testing.T) {
+	t.Setenv("NACOS_TEST_HOST", "db.local")
+	cases := []struct {
+		name       string
+		configType ConfigType
+		content    string
+		strict     bool
+		want       map[string]any
+	}{
+		{
+			name:       "env and default",
+			configType: ConfigTypeYaml,
+			content:    "host: ${NACOS_TEST_HOST}\nport: ${NACOS_TEST_PORT:3306}\nurl: \"${NACOS_TEST_HOST}:${NACOS_TEST_PORT:3306}\"\n",
+			want:       map[string]any{"host": "db.local", "port": 3306, "url": "db.local:3306"},
+		},
+		{
+			name:       "nested default",
+			configType: ConfigTypeYaml,
+			content:    "name: ${NACOS_TEST_MISSING:${NACOS_TEST_HOST}}\n",
+			want:       map[string]any{"name": "db.local"},
+		},
+		{
+			name:       "key reference",
+			configType: ConfigTypeYaml,
+			content:    "server:\n  port: 8080\nlist:\n  - name: a\nurl: http://localhost:${server.port}/${list[0].name}\n",
+			want:       map[string]any{"url": "http://localhost:8080/a"},
+		},
+		{
+			name:       "recursive reference",
+			configType: ConfigTypeJson,
+			content:    `{"a": "${b}", "b": "${c}", "c": "v"}`,
+			want:       map[string]any{"a": "v", "b": "v"},
+		},
+		{
+			name:       "unresolved kept",
+			configType: ConfigTypeYaml,
+			content:    "a: ${NACOS_TEST_MISSING}\n",
+			want:       map[string]any{"a": "${NACOS_TEST_MISSING}"},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			content, err := Interpolate(c.content, c.configType, c.strict)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var tree map[string]any
+			if err = deserializeConfig(content, c.configType, &tree); err != nil {
+				t.Fatalf("%v\n%s", err, content)
+			}
+			for k, want := range c.want {
+				got := tree[k]
+				if n, ok := got.(float64); ok {
+					got = int(n)
+				}
+				if got != want {
+					t.Errorf("%s = %#v, want %#v", k, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestInterpolateErrors(t *testing.T) {
+	cases := []struct {
+		name    string
+		content string
+		strict  bool
+		want    string
+	}{
+		{"cycle", "a: ${b}\nb: ${a}\n", false, "circular"},
+		{"self", "a: ${a}\n", false, "circular"},
+		{"non scalar", "a: ${b}\nb:\n  c: 1\n", false, "non scalar"},
+		{"strict unresolved", "a: ${NACOS_TEST_MISSING}\n", true, "unresolved"},
+		{"strict unclosed", "a: ${NACOS_TEST_MISSING\n", true, "unclosed"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			_, err := Interpolate(c.content, ConfigTypeYaml, c.strict)
+			if err == nil || !strings.Contains(err.Error(), c.want) {
+				t.Errorf("err = %v, want %q", err, c.want)
+			}
+		})
+	}
+}
+
+func TestInterpolateEscaping(t *testing.T) {
+	values := []string{`p"w`, `a\b`, "line1\nline2", `x", "admin": true, "y": "`, "k: v # c", "- item", "true"}
+	for _, v := range values {
+		t.Setenv("NACOS_TEST_PASS", v)
+		for _, c := range []struct {
+			configType ConfigType
+			content    string
+		}{
+			{ConfigTypeJson, `{"pwd": "${NACOS_TEST_PASS}"}`},
+			{ConfigTypeYaml, "pwd: \"${NACOS_TEST_PASS}\"\n"},
+			{ConfigTypeYaml, "pwd: '${NACOS_TEST_PASS}'\n"},
+			{ConfigTypeToml, `pwd = "${NACOS_TEST_PASS}"`},
+			{ConfigTypeProperties, "pwd=${NACOS_TEST_PASS}\n"},
+		} {
+			content, err := Interpolate(c.content, c.configType, true)
+			if err != nil {
+				t.Fatalf("%s %q: %v", c.configType, v, err)
+			}
+			var tree map[string]any
+			if err = deserializeConfig(content, c.configType, &tree); err != nil {
+				t.Fatalf("%s %q: %v\n%s", c.configType, v, err, content)
+			}
+			// properties的值会按内容推断类型 比较字符串形式
+			if len(tree) != 1 || fmt.Sprint(tree["pwd"]) != v {
+				t.Errorf("%s %q: got %#v", c.configType, v, tree)
+			}
+		}
+	}
+}
+
+func TestInterpolatePlainYaml(t *testing.T) {
+	t.Setenv("NACOS_TEST_PASS", `p"w: x`)
+	content, err := Interpolate("pwd: ${NACOS_TEST_PASS}\n", ConfigTypeYaml, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var v struct {
+		Pwd string `yaml:"pwd"`
+	}
+	if err = deserializeConfig(content, ConfigTypeYaml, &v); err != nil {
+		t.Fatal(err)
+	}
+	if v.Pwd != `p"w: x` {
+		t.Errorf("pwd = %q", v.Pwd)
+	}
+}
+
+func TestInterpolateText(t *testing.T) {
+	t.Setenv("NACOS_TEST_HOST", "db.local")
+	content, err := Interpolate("host=${NACOS_TEST_HOST} port=${NACOS_TEST_PORT:1}", ConfigTypeText, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if content != "host=db.local port=1" {
+		t.Errorf("content = %q", content)
+	}
+}
+
+func TestInterpolateRetype(t *testing.T) {
+	t.Setenv("NACOS_TEST_PORT", "8080")
+	t.Setenv("NACOS_TEST_HOST", "db.local")
+	cases := []struct {
+		configType ConfigType
+		content    string
+	}{
+		{ConfigTypeJson, `{"port": "${NACOS_TEST_PORT}", "debug": "${NACOS_TEST_DEBUG:true}", "host": "${NACOS_TEST_HOST}", "url": "${NACOS_TEST_HOST}:${NACOS_TEST_PORT}"}`},
+		{ConfigTypeToml, "port = ${NACOS_TEST_PORT}\ndebug = \"${NACOS_TEST_DEBUG:true}\"\nhost = ${NACOS_TEST_HOST}\nurl = \"${NACOS_TEST_HOST}:${NACOS_TEST_PORT}\"\n"},
+	}
+	for _, c := range cases {
+		content, err := Interpolate(c.content, c.configType, true)
+		if err != nil {
+			t.Fatalf("%s: %v", c.configType, err)
+		}
+		var v struct {
+			Port  int    `json:"port" toml:"port"`
+			Debug bool   `json:"debug" toml:"debug"`
+			Host  string `json:"host" toml:"host"`
+			Url   string `json:"url" toml:"url"`
+		}
+		if err = deserializeConfig(content, c.configType, &v); err != nil {
+			t.Fatalf("%s: %v\n%s", c.configType, err, content)
+		}
+		if v.Port != 8080 || !v.Debug || v.Host != "db.local" || v.Url != "db.local:8080" {
+			t.Errorf("%s: got %+v", c.configType, v)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package nacosstarter
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// 配置键路径中的一段 如 a.b[0].c 解析为 a, b, [0], c
+type pathSegment struct {
+	key   string
+	index int
+	isIdx bool
+}
+
+func (s pathSegment) String() string {
+	if s.isIdx {
+		return "[" + strconv.Itoa(s.index) + "]"
+	}
+	return s.key
+}
+
+// 解析形如 a.b[0].c 的键路径
+func parseKeyPath(path string) ([]pathSegment, error) {
+	var segments []pathSegment
+	var key strings.Builder
+	flush := func() {
+		if key.Len() > 0 {
+			segments = append(segments, pathSegment{key: key.String()})
+			key.Reset()
+		}
+	}
+	for i := 0; i < len(path); i++ {
+		switch c := path[i]; c {
+		case '.':
+			flush()
+		case '[':
+			flush()
+			end := strings.IndexByte(path[i:], ']')
+			if end < 0 {
+				return nil, fmt.Errorf("bad key path %s: unclosed [", path)
+			}
+			index, err := strconv.Atoi(path[i+1 : i+end])
+			if err != nil || index < 0 {
+				return nil, fmt.Errorf("bad key path %s: invalid index %s", path, path[i+1:i+end])
+			}
+			segments = append(segments, pathSegment{index: index, isIdx: true})
+			i += end
+		default:
+			key.WriteByte(c)
+		}
+	}
+	flush()
+	if len(segments) == 0 {
+		return nil, fmt.Errorf("bad key path %q", path)
+	}
+	return segments, nil
+}
+
+// 将键路径段拼接为 a.b[0].c 形式
+func joinKeyPath(segments []pathSegment) string {
+	var b strings.Builder
+	for i, s := range segments {
+		if !s.isIdx && i > 0 {
+			b.WriteByte('.')
+		}
+		b.WriteString(s.String())
+	}
+	return b.String()
+}
+
+// 在通用树结构中按键路径查找值
+func lookupTree(tree any, segments []pathSegment) (any, bool) {
+	current := tree
+	for _, s := range segments {
+		if s.isIdx {
+			list, ok := current.([]any)
+			if !ok || s.index >= len(list) {
+				return nil, false
+			}
+			current = list[s.index]
+			continue
+		}
+		m, ok := current.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		current, ok = m[s.key]
+		if !ok {
+			return nil, false
+		}
+	}
+	return current, true
+}
@@ -0,0 +1,243 @@
+package nacosstarter
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigSource 分层配置中的一层
+type ConfigSource struct {
+	Group  string
+	DataId string
+	Type   ConfigType
+	// 该层配置不存在时视为错误 默认忽略不存在的层
+	Required bool
+}
+
+// LayeredConfigSetting 多层配置合并设置
+//
+// 合并规则:
+//   - Sources按顺序合并，后面的层优先级更高
+//   - 对象(map)逐键递归合并
+//   - 数组与标量整体替换，不做元素级合并
+//   - 后层显式设置为null的键同样会覆盖前层的值
+//   - yaml与json层的标量按原文合并，数字精度与日期等格式保持不变
+type LayeredConfigSetting struct {
+	Sources []ConfigSource
+	// 合并结果绑定到Value时使用的格式 决定结构体使用的tag 默认为第一层的格式
+	Type  ConfigType
+	Watch bool
+	Value any
+
+	// 同ConfigFileSetting 作用于合并后的结果 占位符可以引用任意一层中的键
+	Validate          func(value any) error
+	OnError           func(dataId string, err error)
+	Interpolate       bool
+	StrictPlaceholder bool
+	// 同ConfigFileSetting 每一层独立合并变更
+	Debounce time.Duration
+
+	mu          sync.Mutex
+	layers      []string
+	staleLayers map[int]bool
+	target      *ConfigFileSetting
+}
+
+// Status 获取合并结果的加载状态
+func (l *LayeredConfigSetting) Status() ConfigStatus {
+	if l.target == nil {
+		return ConfigStatus{}
+	}
+	return l.target.Status()
+}
+
+// 合并后配置的标识 用于日志与回调
+func (l *LayeredConfigSetting) name() string {
+	names := make([]string, 0, len(l.Sources))
+	for _, s := range l.Sources {
+		names = append(names, s.Group+"/"+s.DataId)
+	}
+	return strings.Join(names, "+")
+}
+
+// LoadAndWatchLayeredConfig 按顺序加载多个配置层并深度合并到同一个目标
+// 开启Watch后，任意一层发生变化都会使用各层最新内容重新合并整个配置栈
+func LoadAndWatchLayeredConfig(setting *LayeredConfigSetting) error {
+	m, err := getDefaultManager()
+	if err != nil {
+		return err
+	}
+	return m.LoadAndWatchLayeredConfig(setting)
+}
+
+// LoadAndWatchLayeredConfig 使用当前Manager的配置客户端加载分层配置 详见包级函数LoadAndWatchLayeredConfig
+func (m *Manager) LoadAndWatchLayeredConfig(setting *LayeredConfigSetting) error {
+	if len(setting.Sources) == 0 {
+		return fmt.Errorf("%w: empty config source", ErrInvalidConfig)
+	}
+	configType := setting.Type
+	if configType == "" {
+		configType = setting.Sources[0].Type
+	}
+	if setting.target == nil {
+		setting.target = &ConfigFileSetting{
+			DataId:            setting.name(),
+			Type:              configType,
+			Value:             setting.Value,
+			Validate:          setting.Validate,
+			OnError:           setting.OnError,
+			Interpolate:       setting.Interpolate,
+			StrictPlaceholder: setting.StrictPlaceholder,
+		}
+	}
+	setting.layers = make([]string, len(setting.Sources))
+	setting.staleLayers = make(map[int]bool)
+
+	clients := make([]*ConfigClient, len(setting.Sources))
+	for i, source := range setting.Sources {
+		client, err := m.Config(source.Group)
+		if err != nil {
+			return err
+		}
+		clients[i] = client
+		raw, stale, err := client.getConfigOrSnapshot(source.DataId)
+		if err != nil {
+			client.observeApplied(source.DataId, false, err)
+			err = fmt.Errorf("cant load config layer %s: %w", source.DataId, err)
+			setting.target.fail(err)
+			return err
+		}
+		setting.layers[i] = raw
+		if stale {
+			setting.staleLayers[i] = true
+		}
+	}
+	_, err := setting.merge(-1, "")
+	// 合并结果作为每一层的加载结果
+	for i, source := range setting.Sources {
+		clients[i].observeApplied(source.DataId, false, err)
+	}
+	if err != nil {
+		setting.target.fail(err)
+		return err
+	}
+	// 监听或快照恢复开始后merge可能并发修改各层状态 先复制初始加载的结果
+	layers := append([]string{}, setting.layers...)
+	staleLayers := maps.Clone(setting.staleLayers)
+	for i, source := range setting.Sources {
+		reload := clients[i].reloader(source.DataId, func(content string) (bool, error) {
+			return setting.merge(i, content)
+		}, func(err error) {
+			setting.target.fail(fmt.Errorf("cant reload config layer %s: %w", source.DataId, err))
+		})
+		if staleLayers[i] {
+			clients[i].recoverFromServer(source.DataId, reload)
+		} else {
+			clients[i].saveSnapshot(source.DataId, layers[i])
+		}
+		if !setting.Watch {
+			continue
+		}
+		if _, err := clients[i].watchReload(source.DataId, setting.Debounce, reload); err != nil {
+			err = fmt.Errorf("cant watch config layer %s: %w", source.DataId, err)
+			setting.target.fail(err)
+			return err
+		}
+	}
+	return nil
+}
+
+// 更新第index层的内容(index<0时不更新)并重新合并所有层
+// 该层内容或合并结果与之前相同时changed为false
+func (l *LayeredConfigSetting) merge(index int, content string) (changed bool, err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if index >= 0 && l.layers[index] == content {
+		// 该层内容未变化 无需重新合并
+		delete(l.staleLayers, index)
+		if len(l.staleLayers) == 0 {
+			l.target.clearStale()
+		}
+		return false, nil
+	}
+	layers := append([]string{}, l.layers...)
+	if index >= 0 {
+		layers[index] = content
+	}
+	var merged *yaml.Node
+	for i, source := range l.Sources {
+		if layers[i] == "" {
+			if source.Required {
+				return false, configNotFound(source.Group, source.DataId)
+			}
+			continue
+		}
+		node, err := layerNode(layers[i], source.Type)
+		if err != nil {
+			return false, withConfigSource(err, source.Group, source.DataId)
+		}
+		merged = mergeNode(merged, node)
+	}
+	content, err = encodeNode(merged, l.target.Type)
+	if err != nil {
+		return false, err
+	}
+	if changed, err = l.target.apply(content); err != nil {
+		return false, err
+	}
+	// 合并结果应用成功后才记录新内容，失败时下次合并仍基于上一次有效的层
+	l.layers = layers
+	if index >= 0 {
+		delete(l.staleLayers, index)
+	}
+	// 任意一层仍来自本地快照时 合并结果视为过期
+	if len(l.staleLayers) > 0 {
+		l.target.markStale()
+	}
+	return changed, nil
+}
+
+// 将一层配置解析为yaml节点树 空文档视为空对象
+func layerNode(content string, configType ConfigType) (*yaml.Node, error) {
+	node, err := documentNode(content, configType)
+	if err != nil {
+		return nil, err
+	}
+	if node == nil {
+		return &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}, nil
+	}
+	if node.Kind != yaml.MappingNode {
+		return nil, newDecodeError(content, configType, errors.New("layered config must be an object"))
+	}
+	return node, nil
+}
+
+// 将src深度合并到dst 对象逐键合并，其他类型整体替换，不修改入参
+func mergeNode(dst, src *yaml.Node) *yaml.Node {
+	if dst == nil || dst.Kind != yaml.MappingNode || src.Kind != yaml.MappingNode {
+		return src
+	}
+	merged := *dst
+	merged.Content = append([]*yaml.Node{}, dst.Content...)
+	for i := 0; i+1 < len(src.Content); i += 2 {
+		key, value := src.Content[i], src.Content[i+1]
+		found := false
+		for j := 0; j+1 < len(merged.Content); j += 2 {
+			if merged.Content[j].Value == key.Value {
+				merged.Content[j+1] = mergeNode(merged.Content[j+1], value)
+				found = true
+				break
+			}
+		}
+		if !found {
+			merged.Content = append(merged.Content, key, value)
+		}
+	}
+	return &merged
+}
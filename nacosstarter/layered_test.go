@@ -0,0 +1,181 @@
+package nacosstarter
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/nacos-group/nacos-sdk-go/v2/clients/config_client"
+	"github.com/nacos-group/nacos-sdk-go/v2/vo"
+)
+
+type layeredTestConfig struct {
+	Id      int64             `json:"id" yaml:"id"`
+	Ver     string            `json:"ver" yaml:"ver"`
+	Release string            `json:"release" yaml:"release"`
+	Name    string            `json:"name" yaml:"name"`
+	Labels  map[string]string `json:"labels" yaml:"labels"`
+	Hosts   []string          `json:"hosts" yaml:"hosts"`
+	Remove  *string           `json:"remove" yaml:"remove"`
+}
+
+func mergeLayers(t *testing.T, targetType ConfigType, sources []ConfigSource, layers []string) (layeredTestConfig, error) {
+	t.Helper()
+	var v layeredTestConfig
+	l := &LayeredConfigSetting{
+		Sources:     sources,
+		layers:      layers,
+		staleLayers: make(map[int]bool),
+		target:      &ConfigFileSetting{DataId: "layered", Type: targetType, Value: &v},
+	}
+	_, err := l.merge(-1, "")
+	return v, err
+}
+
+func TestLayeredMergeKeepsScalars(t *testing.T) {
+	override := `{"name": "dev", "labels": {"b": "3"}, "hosts": ["h3"], "remove": null}`
+	cases := []struct {
+		targetType ConfigType
+		base       string
+	}{
+		{ConfigTypeYaml, "id: 9007199254740993\nver: 1.10\nrelease: 2024-01-01\nname: base\nlabels:\n  a: \"1\"\n  b: \"2\"\nhosts: [h1, h2]\nremove: x\n"},
+		// json无法将数字解码到字符串字段 版本号与日期使用引号
+		{ConfigTypeJson, "id: 9007199254740993\nver: \"1.10\"\nrelease: \"2024-01-01\"\nname: base\nlabels:\n  a: \"1\"\n  b: \"2\"\nhosts: [h1, h2]\nremove: x\n"},
+	}
+	for _, c := range cases {
+		t.Run(string(c.targetType), func(t *testing.T) {
+			v, err := mergeLayers(t, c.targetType, []ConfigSource{
+				{DataId: "base.yaml", Type: ConfigTypeYaml},
+				{DataId: "dev.json", Type: ConfigTypeJson},
+			}, []string{c.base, override})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if v.Id != 9007199254740993 {
+				t.Errorf("id = %d", v.Id)
+			}
+			if v.Ver != "1.10" || v.Release != "2024-01-01" {
+				t.Errorf("ver = %q release = %q", v.Ver, v.Release)
+			}
+			if v.Name != "dev" || v.Labels["a"] != "1" || v.Labels["b"] != "3" {
+				t.Errorf("merged = %+v", v)
+			}
+			if len(v.Hosts) != 1 || v.Hosts[0] != "h3" {
+				t.Errorf("hosts = %v", v.Hosts)
+			}
+			if v.Remove != nil {
+				t.Errorf("remove = %v", *v.Remove)
+			}
+		})
+	}
+}
+
+func TestLayeredMergeOtherFormats(t *testing.T) {
+	v, err := mergeLayers(t, ConfigTypeYaml, []ConfigSource{
+		{DataId: "base.toml", Type: ConfigTypeToml},
+		{DataId: "dev.properties", Type: ConfigTypeProperties},
+	}, []string{"id = 9007199254740993\nname = \"base\"\n", "name=dev\n"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.Id != 9007199254740993 || v.Name != "dev" {
+		t.Errorf("merged = %+v", v)
+	}
+}
+
+func TestLayeredMergeAliasAndMissing(t *testing.T) {
+	base := "defaults: &d\n  a: \"1\"\nlabels: *d\n"
+	v, err := mergeLayers(t, ConfigTypeYaml, []ConfigSource{
+		{DataId: "base.yaml", Type: ConfigTypeYaml},
+		{DataId: "missing.yaml", Type: ConfigTypeYaml},
+		{DataId: "dev.yaml", Type: ConfigTypeYaml},
+	}, []string{base, "", "defaults: {}\nname: dev\n"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.Labels["a"] != "1" || v.Name != "dev" {
+		t.Errorf("merged = %+v", v)
+	}
+
+	_, err = mergeLayers(t, ConfigTypeYaml, []ConfigSource{
+		{DataId: "base.yaml", Type: ConfigTypeYaml, Required: true},
+	}, []string{""})
+	if err == nil {
+		t.Error("required layer missing but no error")
+	}
+}
+
+func TestLayeredMergeRejectsBadLayer(t *testing.T) {
+	for _, layer := range []struct {
+		configType ConfigType
+		content    string
+	}{
+		{ConfigTypeJson, `{"a": 1} {"b": 2}`},
+		{ConfigTypeJson, `[1, 2]`},
+		{ConfigTypeYaml, "a: [\n"},
+	} {
+		_, err := mergeLayers(t, ConfigTypeYaml, []ConfigSource{{DataId: "bad", Type: layer.configType}}, []string{layer.content})
+		if err == nil {
+			t.Errorf("%s layer %q: expect error", layer.configType, layer.content)
+		}
+	}
+}
+
+// 使用不依赖服务端的sdk构建Manager 默认namespace的所有group共享该sdk
+func newTestManager(client config_client.IConfigClient) *Manager {
+	return &Manager{
+		configInstance:  client,
+		configInstances: map[string]config_client.IConfigClient{"": client},
+		configClient:    make(map[clientKey]*ConfigClient),
+		observer:        NopObserver{},
+		done:            make(chan struct{}),
+	}
+}
+
+// 与sdk一样在独立的goroutine中送达监听后的首次通知
+type asyncConfigClient struct {
+	*fakeConfigClient
+	wg sync.WaitGroup
+}
+
+func (f *asyncConfigClient) ListenConfig(param vo.ConfigParam) error {
+	if err := f.fakeConfigClient.ListenConfig(param); err != nil {
+		return err
+	}
+	f.mu.Lock()
+	content := f.contents[param.DataId] + "\n# pushed\n"
+	f.mu.Unlock()
+	f.wg.Add(1)
+	go func() {
+		defer f.wg.Done()
+		param.OnChange("", param.Group, param.DataId, content)
+	}()
+	return nil
+}
+
+func TestLayeredWatchConcurrentNotify(t *testing.T) {
+	fake := &asyncConfigClient{fakeConfigClient: newFakeConfigClient(map[string]string{
+		"base.yaml":  "name: base\nlabels:\n  a: \"1\"\n",
+		"dev.yaml":   "name: dev\n",
+		"local.yaml": "labels:\n  b: \"2\"\n",
+	})}
+	m := newTestManager(fake)
+	// 每层使用不同的group 对应不同的ConfigClient，通知之间没有共享的锁
+	var v layeredTestConfig
+	err := m.LoadAndWatchLayeredConfig(&LayeredConfigSetting{
+		Sources: []ConfigSource{
+			{Group: "g1", DataId: "base.yaml", Type: ConfigTypeYaml},
+			{Group: "g2", DataId: "dev.yaml", Type: ConfigTypeYaml},
+			{Group: "g3", DataId: "local.yaml", Type: ConfigTypeYaml},
+		},
+		Type:  ConfigTypeYaml,
+		Value: &v,
+		Watch: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fake.wg.Wait()
+	if v.Name != "dev" || v.Labels["a"] != "1" || v.Labels["b"] != "2" {
+		t.Errorf("merged = %+v", v)
+	}
+}
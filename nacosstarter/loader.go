@@ -1,47 +1,54 @@
 package nacosstarter
 
 import (
-	"errors"
-	"reflect"
+	"fmt"
 	"sync"
 	"time"
 
-	"github.com/acexy/golang-toolkit/logger"
-	"github.com/acexy/golang-toolkit/util/json"
 	"github.com/golang-acexy/starter-parent/parent"
-	"github.com/nacos-group/nacos-sdk-go/v2/clients"
 	"github.com/nacos-group/nacos-sdk-go/v2/clients/config_client"
 	"github.com/nacos-group/nacos-sdk-go/v2/clients/naming_client"
 	"github.com/nacos-group/nacos-sdk-go/v2/common/constant"
 	"github.com/nacos-group/nacos-sdk-go/v2/model"
 	"github.com/nacos-group/nacos-sdk-go/v2/vo"
-	"gopkg.in/yaml.v3"
 )
 
-var configInstance config_client.IConfigClient
-var namingInstance naming_client.INamingClient
-var nm *nacosManager
-var namespace string
-
-// 针对多group的nacos实例管理器
-type nacosManager struct {
-	configLocker sync.Mutex
-	namingLocker sync.Mutex
+type ConfigClient struct {
+	mu        sync.Mutex
+	m         *Manager
+	namespace string
+	group     string
+	client    config_client.IConfigClient
+	// key = md5(dataId + group) 每个dataId仅对应一个底层监听
+	watched map[string]*configWatch
+	// key = watchId value = 所属监听的key
+	watchIds map[string]string
+	watchSeq uint64
+	// key = watchId
+	groupWatches map[string]*groupWatch
+
+	valueMu sync.Mutex
+	// key = dataId GetValue使用的配置树缓存
+	values map[string]*valueCache
+}
 
-	// key = groupName
-	configClient map[string]*ConfigClient
-	namingClient map[string]*NamingClient
+// 同一个dataId的底层监听及其所有订阅者
+type configWatch struct {
+	param       vo.ConfigParam
+	subscribers []configSubscriber
 }
 
-type ConfigClient struct {
-	mu      sync.Mutex
-	group   string
-	watched map[string]*vo.ConfigParam
+type configSubscriber struct {
+	id    string
+	watch func(namespace, group, dataId, data string)
 }
 
 type NamingClient struct {
 	mu         sync.Mutex
+	m          *Manager
+	namespace  string
 	group      string
+	client     naming_client.INamingClient
 	registered map[string]vo.RegisterInstanceParam
 	watched    map[string]*vo.SubscribeParam
 }
@@ -66,56 +73,42 @@ type RegisteredInstance struct {
 	InstanceIdentifier string
 }
 
-func deserializeConfig(content string, configType ConfigType, value any) error {
-	switch configType {
-	case ConfigTypeYaml:
-		// 确保value是指针
-		rv := reflect.ValueOf(value)
-		if rv.Kind() != reflect.Ptr || rv.IsNil() {
-			return errors.New("value must be a non-nil pointer")
-		}
-		elem := rv.Elem()
-		newValue := reflect.New(elem.Type()).Interface()
-		err := yaml.Unmarshal([]byte(content), newValue)
-		if err != nil {
-			return err
-		}
-		elem.Set(reflect.ValueOf(newValue).Elem())
-		return nil
-	case ConfigTypeJson:
-		return json.ParseJsonError(content, value)
+// GetConfigClient 获取默认namespace下指定group的配置客户端
+func GetConfigClient(group string) (*ConfigClient, error) {
+	m, err := getDefaultManager()
+	if err != nil {
+		return nil, err
 	}
-	return errors.New("known config type " + string(configType))
+	return m.Config(group)
 }
 
-func GetConfigClient(group string) (*ConfigClient, error) {
-	if configInstance == nil {
-		return nil, errors.New("disabled config client")
-	}
-	nm.configLocker.Lock()
-	defer nm.configLocker.Unlock()
-	v, ok := nm.configClient[group]
-	if ok {
-		return v, nil
+// GetNamespaceConfigClient 获取指定namespace与group的配置客户端
+// 每个namespace首次使用时创建独立的底层sdk实例，并在Stop时关闭
+func GetNamespaceConfigClient(ns, group string) (*ConfigClient, error) {
+	m, err := getDefaultManager()
+	if err != nil {
+		return nil, err
 	}
-	v = &ConfigClient{group: group, watched: make(map[string]*vo.ConfigParam)}
-	nm.configClient[group] = v
-	return v, nil
+	return m.NamespaceConfig(ns, group)
 }
 
+// GetNamingClient 获取默认namespace下指定group的服务发现客户端
 func GetNamingClient(group string) (*NamingClient, error) {
-	if namingInstance == nil {
-		return nil, errors.New("disabled discover client")
+	m, err := getDefaultManager()
+	if err != nil {
+		return nil, err
 	}
-	nm.namingLocker.Lock()
-	defer nm.namingLocker.Unlock()
-	v, ok := nm.namingClient[group]
-	if ok {
-		return v, nil
+	return m.Naming(group)
+}
+
+// GetNamespaceNamingClient 获取指定namespace与group的服务发现客户端
+// 每个namespace首次使用时创建独立的底层sdk实例，并在Stop时关闭
+func GetNamespaceNamingClient(ns, group string) (*NamingClient, error) {
+	m, err := getDefaultManager()
+	if err != nil {
+		return nil, err
 	}
-	v = &NamingClient{group: group, registered: make(map[string]vo.RegisterInstanceParam), watched: make(map[string]*vo.SubscribeParam)}
-	nm.namingClient[group] = v
-	return v, nil
+	return m.NamespaceNaming(ns, group)
 }
 
 type NacosServerConfig struct {
@@ -131,11 +124,37 @@ type ConfigFileSetting struct {
 	Type   ConfigType
 	Watch  bool
 	Value  any
+
+	// 配置校验函数 在新内容反序列化为全新副本后执行，校验通过才会替换Value
+	// 若Value类型实现了Validatable接口，其Validate方法将先于该函数执行
+	Validate func(value any) error
+	// 加载或热更新失败时的回调 失败时Value保持上一次有效的值
+	OnError func(dataId string, err error)
+
+	// 开启后在反序列化前解析内容中的 ${ENV}、${ENV:default} 与 ${key.path} 占位符 详见Interpolate
+	Interpolate bool
+	// 存在无法解析的占位符时视为加载失败 默认保留占位符原文
+	StrictPlaceholder bool
+
+	// 变更合并窗口 窗口内的多次变更只应用最后一次，默认每次变更立即应用
+	Debounce time.Duration
+
+	group string
+	// 串行执行apply 避免并发的变更互相覆盖
+	applyLocker  sync.Mutex
+	statusLocker sync.Mutex
+	status       ConfigStatus
+	// 最近一次成功应用的原始内容md5 内容未变化的通知将被忽略
+	md5 string
 }
 
 type InitConfigSettings struct {
+	// 未指定DataId的配置将根据ApplicationName、ActiveProfiles与FileExtension按约定解析dataId并分层合并加载
 	ConfigSetting []*ConfigFileSetting
-	GroupName     string
+	// 默认DEFAULT_GROUP
+	GroupName string
+	// 通过结构体字段的nacos tag自动加载配置 未指定group的字段使用GroupName 详见BindStruct
+	BindStruct any
 }
 
 type NacosConfig struct {
@@ -151,6 +170,32 @@ type NacosConfig struct {
 	// 该设置将在nacos就绪后立即执行，适用于初始化配置其他模块可以立即在后续读取
 	InitConfigSettings *InitConfigSettings
 
+	// 应用名称 用于按约定解析 ${app}.${ext} 与 ${app}-${profile}.${ext} 形式的dataId
+	ApplicationName string
+	// 激活的环境 按顺序加载，后面的环境覆盖前面的环境
+	ActiveProfiles []string
+	// 按约定解析dataId时使用的文件扩展名 默认yaml
+	FileExtension string
+
+	// 配置值解密器 配置中形如 ENC(...) 的值将在反序列化时被解密 进程内所有starter共用，等同于调用SetDecryptor
+	Decryptor Decryptor
+
+	// 本地快照目录 设置后每次成功应用的配置都会保存到该目录
+	// 启动或加载时服务端不可用将使用快照中的内容，并在服务端恢复后自动替换
+	// 设置后将同时开启ClientConfig.DisableUseSnapShot，由快照代替sdk自身的缓存回退
+	SnapshotDir string
+	// 服务端不可用时重试获取配置的间隔 默认30s
+	SnapshotRetryInterval time.Duration
+
+	// WatchGroup扫描分组中配置的间隔 默认30s
+	GroupWatchInterval time.Duration
+
+	// 动态日志级别 设置后监听该配置并实时调整日志与Nacos SDK的日志级别
+	LogLevelSetting *LogLevelSetting
+
+	// 配置与服务发现活动的观察者 可使用子包metrics导出Prometheus指标
+	Observer Observer
+
 	// Nacos启动完毕后执行的函数
 	AfterInit func(config config_client.IConfigClient, naming naming_client.INamingClient)
 }
@@ -160,9 +205,15 @@ type NacosStarter struct {
 	LazyConfig func() NacosConfig
 
 	config       *NacosConfig
+	manager      *Manager
 	NacosSetting *parent.Setting
 }
 
+// Manager 获取当前starter的客户端管理器 未启动时返回nil
+func (n *NacosStarter) Manager() *Manager {
+	return n.manager
+}
+
 func (n *NacosStarter) getConfig() *NacosConfig {
 	if n.config == nil {
 		var config NacosConfig
@@ -188,86 +239,115 @@ func (n *NacosStarter) Start() (interface{}, error) {
 	config := n.getConfig()
 
 	if config.DisableDiscovery && config.DisableConfig {
-		return nil, errors.New("config and discover modules are disabled")
+		return nil, fmt.Errorf("%w: config and discover modules are disabled", ErrInvalidConfig)
 	}
-	if config.ServerConfig == nil || config.ClientConfig == nil || len(config.ServerConfig.Services) == 0 {
-		return nil, errors.New("bad nacos config")
+	if config.ServerConfig == nil || config.ClientConfig == nil {
+		return nil, fmt.Errorf("%w: server and client config are required", ErrInvalidConfig)
 	}
-
-	nm = &nacosManager{configClient: make(map[string]*ConfigClient), namingClient: make(map[string]*NamingClient)}
 	if len(config.ServerConfig.Services) == 0 {
-		return nil, errors.New("bad service config")
+		return nil, fmt.Errorf("%w: empty server services", ErrInvalidConfig)
 	}
-	if config.ClientConfig.ClientConfig.NamespaceId == "public" {
-		config.ClientConfig.ClientConfig.NamespaceId = ""
+	if n.manager != nil {
+		return nil, ErrAlreadyStarted
 	}
-	namespace = config.ClientConfig.NamespaceId
-	if !config.DisableConfig {
-		cc, err := clients.NewConfigClient(vo.NacosClientParam{
-			ServerConfigs: config.ServerConfig.Services,
-			ClientConfig:  config.ClientConfig.ClientConfig,
-		})
-		if err != nil {
-			return nil, err
-		}
-		configInstance = cc
-		if config.InitConfigSettings != nil && len(config.InitConfigSettings.ConfigSetting) > 0 && config.InitConfigSettings.GroupName != "" {
-			client, _ := GetConfigClient(config.InitConfigSettings.GroupName)
-			client.LoadAndWatchConfig(config.InitConfigSettings.ConfigSetting)
+	if config.Decryptor != nil {
+		SetDecryptor(config.Decryptor)
+	}
+	m := newManager(config)
+	if err := m.start(config); err != nil {
+		m.stop(0)
+		return nil, err
+	}
+	n.manager = m
+	// 第一个启动的starter作为包级函数的默认实例
+	defaultManager.CompareAndSwap(nil, m)
+	// 启动失败时撤销已发布的实例并关闭sdk 允许再次调用Start
+	fail := func(err error) (interface{}, error) {
+		n.manager = nil
+		defaultManager.CompareAndSwap(m, nil)
+		m.stop(0)
+		return nil, err
+	}
+	if !config.DisableConfig && config.LogLevelSetting != nil {
+		if err := m.watchLogLevels(config.LogLevelSetting); err != nil {
+			return fail(err)
 		}
 	}
-	if !config.DisableDiscovery {
-		nc, err := clients.NewNamingClient(
-			vo.NacosClientParam{
-				ClientConfig:  config.ClientConfig.ClientConfig,
-				ServerConfigs: config.ServerConfig.Services,
-			},
-		)
-		if err != nil {
-			return nil, err
+	if !config.DisableConfig && config.InitConfigSettings != nil {
+		if err := config.loadInitConfigSettings(m); err != nil {
+			return fail(err)
 		}
-		namingInstance = nc
 	}
 	if config.AfterInit != nil {
-		config.AfterInit(configInstance, namingInstance)
+		config.AfterInit(m.configInstance, m.namingInstance)
 	}
 	return nil, nil
 }
 
-func (n *NacosStarter) Stop(maxWaitTime time.Duration) (gracefully, stopped bool, err error) {
-	if configInstance != nil {
-		configInstance.CloseClient()
+// 加载需要立即初始化的配置
+func (n *NacosConfig) loadInitConfigSettings(m *Manager) error {
+	settings := n.InitConfigSettings
+	group := settings.GroupName
+	if group == "" {
+		group = constant.DEFAULT_GROUP
 	}
-	if namingInstance != nil {
-		done := make(chan interface{})
-		go func() {
-			for _, v := range nm.namingClient {
-				for id, i := range v.registered {
-					flag, err := v.Unregister(id)
-					if err != nil {
-						logger.Logrus().WithError(err).Error("unregister instance failed ip:", i.Ip, "port:", i.Port)
-					} else {
-						logger.Logrus().Traceln("unregister instance ip:", i.Ip, "port:", i.Port, "result:", flag)
-					}
-				}
-			}
-			namingInstance.CloseClient()
-			done <- true
-		}()
-		select {
-		case <-done:
-			return true, true, nil
-		case <-time.After(maxWaitTime):
-			return false, true, nil
+	if settings.BindStruct != nil {
+		if _, err := bindStruct(m, settings.BindStruct, group); err != nil {
+			return err
 		}
 	}
-	return true, true, nil
+	if len(settings.ConfigSetting) == 0 {
+		return nil
+	}
+	var files []*ConfigFileSetting
+	for _, f := range settings.ConfigSetting {
+		if f.DataId != "" {
+			files = append(files, f)
+			continue
+		}
+		layered, err := n.profileSetting(group, f)
+		if err != nil {
+			return err
+		}
+		if layered == nil {
+			files = append(files, f)
+			continue
+		}
+		// 加载失败已经由配置的OnError与状态记录
+		_ = m.LoadAndWatchLayeredConfig(layered)
+	}
+	if len(files) > 0 {
+		client, err := m.Config(group)
+		if err != nil {
+			return err
+		}
+		client.LoadAndWatchConfig(files)
+	}
+	return nil
 }
 
+func (n *NacosStarter) Stop(maxWaitTime time.Duration) (gracefully, stopped bool, err error) {
+	m := n.manager
+	if m == nil {
+		return true, true, nil
+	}
+	n.manager = nil
+	defaultManager.CompareAndSwap(m, nil)
+	return m.stop(maxWaitTime), true, nil
+}
+
+// RawConfigInstance 默认starter的底层配置sdk实例
 func RawConfigInstance() config_client.IConfigClient {
-	return configInstance
+	if m := defaultManager.Load(); m != nil {
+		return m.configInstance
+	}
+	return nil
 }
 
+// RawNamingInstance 默认starter的底层服务发现sdk实例
 func RawNamingInstance() naming_client.INamingClient {
-	return namingInstance
+	if m := defaultManager.Load(); m != nil {
+		return m.namingInstance
+	}
+	return nil
 }
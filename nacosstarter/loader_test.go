@@ -0,0 +1,84 @@
+package nacosstarter
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/nacos-group/nacos-sdk-go/v2/common/constant"
+)
+
+func TestStartFailureReleasesManager(t *testing.T) {
+	dir := t.TempDir()
+	starter := &NacosStarter{Config: NacosConfig{
+		ServerConfig: &NacosServerConfig{Services: []constant.ServerConfig{{IpAddr: "127.0.0.1", Port: 1}}},
+		ClientConfig: &NacosClientConfig{ClientConfig: &constant.ClientConfig{
+			LogDir:              dir,
+			CacheDir:            dir,
+			NotLoadCacheAtStart: true,
+			TimeoutMs:           100,
+		}},
+		DisableDiscovery: true,
+		// 缺少dataId 启动时加载失败
+		LogLevelSetting: &LogLevelSetting{},
+	}}
+	for i := 0; i < 2; i++ {
+		_, err := starter.Start()
+		if !errors.Is(err, ErrInvalidConfig) || !strings.Contains(err.Error(), "dataId") {
+			t.Fatalf("start #%d err = %v", i, err)
+		}
+		if starter.Manager() != nil {
+			t.Error("manager kept after failed start")
+		}
+		if m := defaultManager.Load(); m != nil {
+			t.Error("default manager kept after failed start")
+		}
+		if _, err = GetConfigClient("DEFAULT_GROUP"); !errors.Is(err, ErrNotStarted) {
+			t.Errorf("GetConfigClient err = %v", err)
+		}
+	}
+}
+
+func TestStartInvalidConfig(t *testing.T) {
+	client := &NacosClientConfig{ClientConfig: &constant.ClientConfig{}}
+	cases := []NacosConfig{
+		{DisableConfig: true, DisableDiscovery: true},
+		{ClientConfig: client},
+		{ServerConfig: &NacosServerConfig{}, ClientConfig: client},
+	}
+	for i, config := range cases {
+		starter := &NacosStarter{Config: config}
+		if _, err := starter.Start(); !errors.Is(err, ErrInvalidConfig) {
+			t.Errorf("case %d err = %v", i, err)
+		}
+	}
+	if _, err := (&NacosStarter{manager: &Manager{}, Config: NacosConfig{
+		ServerConfig: &NacosServerConfig{Services: []constant.ServerConfig{{IpAddr: "127.0.0.1"}}},
+		ClientConfig: client,
+	}}).Start(); !errors.Is(err, ErrAlreadyStarted) {
+		t.Errorf("restart err = %v", err)
+	}
+}
+
+func TestLoadInitConfigSettingsProfile(t *testing.T) {
+	fake := newFakeConfigClient(map[string]string{"app.yaml": "n: 1\n"})
+	m := newTestManager(fake)
+	var v struct {
+		N int `yaml:"n"`
+	}
+	// 未设置GroupName时使用DEFAULT_GROUP
+	f := &ConfigFileSetting{Value: &v}
+	n := &NacosConfig{ApplicationName: "app", InitConfigSettings: &InitConfigSettings{ConfigSetting: []*ConfigFileSetting{f}}}
+	if err := n.loadInitConfigSettings(m); err != nil {
+		t.Fatal(err)
+	}
+	if f.DataId != "app.yaml" || f.group != "DEFAULT_GROUP" || v.N != 1 {
+		t.Errorf("dataId = %s group = %s n = %d", f.DataId, f.group, v.N)
+	}
+
+	// 缺少ApplicationName时返回错误 不再忽略
+	n = &NacosConfig{InitConfigSettings: &InitConfigSettings{ConfigSetting: []*ConfigFileSetting{{Value: &v}}}}
+	if err := n.loadInitConfigSettings(m); !errors.Is(err, ErrInvalidConfig) {
+		t.Errorf("err = %v", err)
+	}
+}
@@ -0,0 +1,281 @@
+package nacosstarter
+
+import (
+	"fmt"
+	"path"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/acexy/golang-toolkit/logger"
+	"github.com/nacos-group/nacos-sdk-go/v2/common/constant"
+	nacoslogger "github.com/nacos-group/nacos-sdk-go/v2/common/logger"
+	"github.com/sirupsen/logrus"
+)
+
+// 记录日志时可以通过该字段指定所属模块 未指定时使用调用方的包路径
+const logModuleField = "module"
+
+const defaultNacosLogLevel = "info"
+
+// LogLevelSetting 动态日志级别配置
+//
+// 配置内容示例(yaml):
+//
+//	# 全局日志级别
+//	level: info
+//	# Nacos SDK自身的日志级别 可选debug、info、warn、error
+//	nacos: warn
+//	# 模块日志级别 模块为包路径前缀或包名，也可以通过日志字段module指定
+//	modules:
+//	  github.com/demo/order: debug
+//	  payment: trace
+//
+// 删除某个键后对应的日志级别恢复为启动时的值
+// 启动时已注册的logrus hook同样按模块级别过滤，之后注册的hook将收到所有级别不低于最详细模块级别的日志
+type LogLevelSetting struct {
+	// 默认DEFAULT_GROUP
+	Group  string
+	DataId string
+	// 默认根据dataId的扩展名推断
+	Type ConfigType
+}
+
+type logLevels struct {
+	Level   string            `json:"level" yaml:"level" toml:"level"`
+	Nacos   string            `json:"nacos" yaml:"nacos" toml:"nacos"`
+	Modules map[string]string `json:"modules" yaml:"modules" toml:"modules"`
+}
+
+type moduleLevel struct {
+	module string
+	level  logrus.Level
+}
+
+// 日志级别控制器 logrus的日志级别设置为所有级别中最详细的一个，再由formatter与hook按模块过滤
+type logLevelController struct {
+	m      *Manager
+	logger *logrus.Logger
+
+	defaultLevel logrus.Level
+	defaultNacos string
+	formatter    logrus.Formatter
+	hooks        logrus.LevelHooks
+
+	mu      sync.RWMutex
+	global  logrus.Level
+	nacos   string
+	modules []moduleLevel
+}
+
+// 仅替换输出前的过滤 不改变原有格式
+type moduleFormatter struct {
+	logrus.Formatter
+	c *logLevelController
+}
+
+func (f *moduleFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	if !f.c.allowed(entry) {
+		return nil, nil
+	}
+	return f.Formatter.Format(entry)
+}
+
+// hook在格式化之前执行 同样需要按模块过滤
+type moduleHook struct {
+	logrus.Hook
+	c *logLevelController
+}
+
+func (h *moduleHook) Fire(entry *logrus.Entry) error {
+	if !h.c.allowed(entry) {
+		return nil
+	}
+	return h.Hook.Fire(entry)
+}
+
+// 加载并监听日志级别配置
+func (m *Manager) watchLogLevels(setting *LogLevelSetting) error {
+	if setting.DataId == "" {
+		return fmt.Errorf("%w: log level setting requires dataId", ErrInvalidConfig)
+	}
+	configType := setting.Type
+	if configType == "" {
+		configType = ConfigTypeOf(setting.DataId)
+	}
+	group := setting.Group
+	if group == "" {
+		group = constant.DEFAULT_GROUP
+	}
+	client, err := m.Config(group)
+	if err != nil {
+		return err
+	}
+	c := newLogLevelController(m, logger.Logrus())
+	m.logLevels = c
+
+	apply := func(content string) {
+		if err := c.apply(content, configType); err != nil {
+			logger.Logrus().WithError(err).Errorln("cant apply log levels:", setting.DataId, "group:", group)
+		}
+	}
+	// 日志级别不影响启动 获取失败时保持默认级别并等待配置变化
+	raw, _, err := client.getConfigOrSnapshot(setting.DataId)
+	if err != nil {
+		logger.Logrus().WithError(err).Warnln("cant load log levels:", setting.DataId, "group:", group)
+	} else {
+		apply(raw)
+	}
+	_, err = client.WatchConfig(setting.DataId, func(namespace, group, dataId, data string) {
+		apply(data)
+	})
+	return err
+}
+
+// 接管logger的formatter与hook 按模块过滤日志
+func newLogLevelController(m *Manager, l *logrus.Logger) *logLevelController {
+	c := &logLevelController{
+		m:            m,
+		logger:       l,
+		defaultLevel: l.GetLevel(),
+		defaultNacos: m.clientConfig.LogLevel,
+		formatter:    l.Formatter,
+	}
+	if c.defaultNacos == "" {
+		c.defaultNacos = defaultNacosLogLevel
+	}
+	c.global = c.defaultLevel
+	c.nacos = c.defaultNacos
+	l.SetFormatter(&moduleFormatter{Formatter: c.formatter, c: c})
+	hooks := make(logrus.LevelHooks)
+	for level, levelHooks := range l.Hooks {
+		for _, hook := range levelHooks {
+			hooks[level] = append(hooks[level], &moduleHook{Hook: hook, c: c})
+		}
+	}
+	c.hooks = l.ReplaceHooks(hooks)
+	return c
+}
+
+// 应用日志级别 内容为空表示配置被删除，全部恢复为默认值
+func (c *logLevelController) apply(content string, configType ConfigType) error {
+	var levels logLevels
+	if content != "" {
+		if err := deserializeConfig(content, configType, &levels); err != nil {
+			return err
+		}
+	}
+	global := c.defaultLevel
+	if levels.Level != "" {
+		level, err := logrus.ParseLevel(levels.Level)
+		if err != nil {
+			return err
+		}
+		global = level
+	}
+	nacos := c.defaultNacos
+	if levels.Nacos != "" {
+		nacos = strings.ToLower(levels.Nacos)
+		switch nacos {
+		case "debug", "info", "warn", "error":
+		default:
+			return fmt.Errorf("unknown nacos log level %s", levels.Nacos)
+		}
+	}
+	modules := make([]moduleLevel, 0, len(levels.Modules))
+	maxLevel := global
+	for module, value := range levels.Modules {
+		level, err := logrus.ParseLevel(value)
+		if err != nil {
+			return fmt.Errorf("module %s: %w", module, err)
+		}
+		modules = append(modules, moduleLevel{module: module, level: level})
+		maxLevel = max(maxLevel, level)
+	}
+	// 优先匹配更具体的模块
+	sort.Slice(modules, func(i, j int) bool {
+		return len(modules[i].module) > len(modules[j].module)
+	})
+
+	c.mu.Lock()
+	nacosChanged := c.nacos != nacos
+	c.global, c.nacos, c.modules = global, nacos, modules
+	c.mu.Unlock()
+	c.logger.SetLevel(maxLevel)
+	if nacosChanged {
+		if err := c.initNacosLogger(nacos); err != nil {
+			return err
+		}
+	}
+	c.logger.Infoln("log level changed global:", global, "nacos:", nacos, "modules:", len(modules))
+	return nil
+}
+
+// 当前Nacos SDK的日志级别 新建的sdk实例同样使用该级别
+func (c *logLevelController) nacosLevel() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.nacos
+}
+
+func (c *logLevelController) allowed(entry *logrus.Entry) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if len(c.modules) == 0 {
+		return entry.Level <= c.global
+	}
+	// 显式指定的模块优先于调用方的包路径
+	module, ok := entry.Data[logModuleField].(string)
+	if !ok {
+		module = callerPackage(entry.Caller)
+	}
+	for _, m := range c.modules {
+		if matchModule(module, m.module) {
+			return entry.Level <= m.level
+		}
+	}
+	return entry.Level <= c.global
+}
+
+// 恢复启动时的日志设置
+func (c *logLevelController) restore() {
+	c.logger.SetFormatter(c.formatter)
+	c.logger.ReplaceHooks(c.hooks)
+	c.logger.SetLevel(c.defaultLevel)
+	c.mu.Lock()
+	nacosChanged := c.nacos != c.defaultNacos
+	c.nacos = c.defaultNacos
+	c.mu.Unlock()
+	if nacosChanged {
+		if err := c.initNacosLogger(c.defaultNacos); err != nil {
+			c.logger.WithError(err).Warnln("cant restore nacos log level")
+		}
+	}
+}
+
+func (c *logLevelController) initNacosLogger(level string) error {
+	clientConfig := c.m.clientConfig
+	clientConfig.LogLevel = level
+	return nacoslogger.InitLogger(nacoslogger.BuildLoggerConfig(clientConfig))
+}
+
+func matchModule(pkg, module string) bool {
+	if pkg == "" {
+		return false
+	}
+	return pkg == module || strings.HasPrefix(pkg, module+"/") || path.Base(pkg) == module
+}
+
+// 从调用方函数名中解析包路径 如 github.com/demo/order.(*Service).Create
+func callerPackage(frame *runtime.Frame) string {
+	if frame == nil {
+		return ""
+	}
+	fn := frame.Function
+	slash := strings.LastIndex(fn, "/")
+	if dot := strings.Index(fn[slash+1:], "."); dot >= 0 {
+		return fn[:slash+1+dot]
+	}
+	return fn
+}
@@ -0,0 +1,91 @@
+package nacosstarter
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/acexy/golang-toolkit/logger"
+	"github.com/nacos-group/nacos-sdk-go/v2/vo"
+	"github.com/sirupsen/logrus"
+)
+
+type recordHook struct {
+	messages []string
+}
+
+func (h *recordHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (h *recordHook) Fire(entry *logrus.Entry) error {
+	h.messages = append(h.messages, entry.Message)
+	return nil
+}
+
+func TestLogLevelModules(t *testing.T) {
+	l := logrus.New()
+	var out bytes.Buffer
+	l.SetOutput(&out)
+	l.SetLevel(logrus.InfoLevel)
+	hook := &recordHook{}
+	l.AddHook(hook)
+
+	c := newLogLevelController(&Manager{}, l)
+	if err := c.apply("level: info\nmodules:\n  order: debug\n", ConfigTypeYaml); err != nil {
+		t.Fatal(err)
+	}
+	hook.messages = nil
+	out.Reset()
+	l.WithField(logModuleField, "order").Debugln("order debug")
+	l.WithField(logModuleField, "payment").Debugln("payment debug")
+	l.WithField(logModuleField, "payment").Infoln("payment info")
+
+	want := []string{"order debug", "payment info"}
+	if strings.Join(hook.messages, ",") != strings.Join(want, ",") {
+		t.Errorf("hook messages = %v, want %v", hook.messages, want)
+	}
+	if strings.Contains(out.String(), "payment debug") || !strings.Contains(out.String(), "order debug") {
+		t.Errorf("output = %s", out.String())
+	}
+
+	c.restore()
+	if l.GetLevel() != logrus.InfoLevel {
+		t.Errorf("level = %s after restore", l.GetLevel())
+	}
+	if _, ok := l.Hooks[logrus.InfoLevel][0].(*recordHook); !ok {
+		t.Errorf("hooks not restored: %T", l.Hooks[logrus.InfoLevel][0])
+	}
+	if _, ok := l.Formatter.(*moduleFormatter); ok {
+		t.Error("formatter not restored")
+	}
+}
+
+// 与sdk一样拒绝未指定group的监听
+type groupRequiredConfigClient struct {
+	*fakeConfigClient
+}
+
+func (f *groupRequiredConfigClient) ListenConfig(param vo.ConfigParam) error {
+	if param.Group == "" {
+		return errors.New("[client.ListenConfig] Group can not be empty")
+	}
+	return f.fakeConfigClient.ListenConfig(param)
+}
+
+func TestWatchLogLevelsDefaultGroup(t *testing.T) {
+	fake := &groupRequiredConfigClient{newFakeConfigClient(map[string]string{"log.yaml": "level: " + logger.Logrus().GetLevel().String() + "\n"})}
+	m := newTestManager(fake)
+	m.clientConfig.LogLevel = defaultNacosLogLevel
+	if err := m.watchLogLevels(&LogLevelSetting{DataId: "log.yaml"}); err != nil {
+		t.Fatal(err)
+	}
+	defer m.logLevels.restore()
+	if !fake.listening("log.yaml") {
+		t.Error("log level config not watched")
+	}
+	if _, ok := m.configClient[clientKey{group: "DEFAULT_GROUP"}]; !ok {
+		t.Error("log level config not loaded from DEFAULT_GROUP")
+	}
+}
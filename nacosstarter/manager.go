@@ -0,0 +1,268 @@
+package nacosstarter
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/acexy/golang-toolkit/logger"
+	"github.com/nacos-group/nacos-sdk-go/v2/clients"
+	"github.com/nacos-group/nacos-sdk-go/v2/clients/config_client"
+	"github.com/nacos-group/nacos-sdk-go/v2/clients/naming_client"
+	"github.com/nacos-group/nacos-sdk-go/v2/common/constant"
+	"github.com/nacos-group/nacos-sdk-go/v2/vo"
+)
+
+// 第一个启动的NacosStarter对应的Manager 包级函数均作用于该实例
+var defaultManager atomic.Pointer[Manager]
+
+// Manager 单个Nacos集群的客户端管理器 由NacosStarter在启动时创建并持有
+// 不同NacosStarter之间的状态相互独立，可以同时连接多个集群
+type Manager struct {
+	configLocker sync.Mutex
+	namingLocker sync.Mutex
+
+	serverConfigs []constant.ServerConfig
+	clientConfig  constant.ClientConfig
+	// 默认namespace
+	namespace string
+
+	// 默认namespace的底层sdk实例 为nil表示对应功能未启用
+	configInstance config_client.IConfigClient
+	namingInstance naming_client.INamingClient
+
+	// key = namespace 每个namespace对应一个底层sdk实例
+	configInstances map[string]config_client.IConfigClient
+	namingInstances map[string]naming_client.INamingClient
+
+	configClient map[clientKey]*ConfigClient
+	namingClient map[clientKey]*NamingClient
+
+	snapshotDir           string
+	snapshotRetryInterval time.Duration
+	groupWatchInterval    time.Duration
+	// sdk未提供的功能通过http开放接口实现
+	api *openApi
+	// 未开启动态日志级别时为nil
+	logLevels *logLevelController
+	// 未设置时为NopObserver
+	observer Observer
+	// Stop时关闭 用于结束后台任务
+	done chan struct{}
+}
+
+type clientKey struct {
+	namespace string
+	group     string
+}
+
+func newManager(config *NacosConfig) *Manager {
+	clientConfig := *config.ClientConfig.ClientConfig
+	clientConfig.NamespaceId = normalizeNamespace(clientConfig.NamespaceId)
+	m := &Manager{
+		serverConfigs:         config.ServerConfig.Services,
+		clientConfig:          clientConfig,
+		namespace:             clientConfig.NamespaceId,
+		configInstances:       make(map[string]config_client.IConfigClient),
+		namingInstances:       make(map[string]naming_client.INamingClient),
+		configClient:          make(map[clientKey]*ConfigClient),
+		namingClient:          make(map[clientKey]*NamingClient),
+		snapshotDir:           config.SnapshotDir,
+		snapshotRetryInterval: config.SnapshotRetryInterval,
+		groupWatchInterval:    config.GroupWatchInterval,
+		observer:              config.Observer,
+		done:                  make(chan struct{}),
+	}
+	if m.observer == nil {
+		m.observer = NopObserver{}
+	}
+	if m.snapshotRetryInterval <= 0 {
+		m.snapshotRetryInterval = defaultSnapshotRetryInterval
+	}
+	if m.groupWatchInterval <= 0 {
+		m.groupWatchInterval = defaultGroupWatchInterval
+	}
+	m.api = newOpenApi(m)
+	return m
+}
+
+// 获取包级函数使用的默认Manager
+func getDefaultManager() (*Manager, error) {
+	m := defaultManager.Load()
+	if m == nil {
+		return nil, ErrNotStarted
+	}
+	return m, nil
+}
+
+// Namespace 默认namespace 空字符串表示public
+func (m *Manager) Namespace() string {
+	return m.namespace
+}
+
+// Config 获取默认namespace下指定group的配置客户端
+func (m *Manager) Config(group string) (*ConfigClient, error) {
+	return m.NamespaceConfig(m.namespace, group)
+}
+
+// NamespaceConfig 获取指定namespace与group的配置客户端
+// 每个namespace首次使用时创建独立的底层sdk实例，并在Stop时关闭
+func (m *Manager) NamespaceConfig(ns, group string) (*ConfigClient, error) {
+	if m.configInstance == nil {
+		return nil, fmt.Errorf("config %w", ErrClientDisabled)
+	}
+	ns = normalizeNamespace(ns)
+	m.configLocker.Lock()
+	defer m.configLocker.Unlock()
+	key := clientKey{namespace: ns, group: group}
+	v, ok := m.configClient[key]
+	if ok {
+		return v, nil
+	}
+	instance, ok := m.configInstances[ns]
+	if !ok {
+		cc, err := clients.NewConfigClient(m.clientParam(ns))
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrServerUnavailable, err)
+		}
+		logger.Logrus().Traceln("created config client for namespace:", ns)
+		instance = cc
+		m.configInstances[ns] = instance
+	}
+	v = &ConfigClient{m: m, namespace: ns, group: group, client: instance, watched: make(map[string]*configWatch), watchIds: make(map[string]string)}
+	m.configClient[key] = v
+	return v, nil
+}
+
+// Naming 获取默认namespace下指定group的服务发现客户端
+func (m *Manager) Naming(group string) (*NamingClient, error) {
+	return m.NamespaceNaming(m.namespace, group)
+}
+
+// NamespaceNaming 获取指定namespace与group的服务发现客户端
+// 每个namespace首次使用时创建独立的底层sdk实例，并在Stop时关闭
+func (m *Manager) NamespaceNaming(ns, group string) (*NamingClient, error) {
+	if m.namingInstance == nil {
+		return nil, fmt.Errorf("discovery %w", ErrClientDisabled)
+	}
+	ns = normalizeNamespace(ns)
+	m.namingLocker.Lock()
+	defer m.namingLocker.Unlock()
+	key := clientKey{namespace: ns, group: group}
+	v, ok := m.namingClient[key]
+	if ok {
+		return v, nil
+	}
+	instance, ok := m.namingInstances[ns]
+	if !ok {
+		nc, err := clients.NewNamingClient(m.clientParam(ns))
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrServerUnavailable, err)
+		}
+		logger.Logrus().Traceln("created naming client for namespace:", ns)
+		instance = nc
+		m.namingInstances[ns] = instance
+	}
+	v = &NamingClient{m: m, namespace: ns, group: group, client: instance, registered: make(map[string]vo.RegisterInstanceParam), watched: make(map[string]*vo.SubscribeParam)}
+	m.namingClient[key] = v
+	return v, nil
+}
+
+// RawConfigInstance 默认namespace的底层配置sdk实例
+func (m *Manager) RawConfigInstance() config_client.IConfigClient {
+	return m.configInstance
+}
+
+// RawNamingInstance 默认namespace的底层服务发现sdk实例
+func (m *Manager) RawNamingInstance() naming_client.INamingClient {
+	return m.namingInstance
+}
+
+// public与空字符串都表示默认namespace
+func normalizeNamespace(ns string) string {
+	if ns == "public" {
+		return ""
+	}
+	return ns
+}
+
+// 以启动配置为模板构建指定namespace的sdk参数
+func (m *Manager) clientParam(ns string) vo.NacosClientParam {
+	clientConfig := m.clientConfig
+	clientConfig.NamespaceId = ns
+	// sdk默认在请求失败时读取自身的缓存并返回成功 开启快照后需要由starter回退并标记Stale
+	if m.snapshotDir != "" {
+		clientConfig.DisableUseSnapShot = true
+	}
+	// 创建sdk实例会重新初始化sdk日志 需要保持动态设置的级别
+	if m.logLevels != nil {
+		clientConfig.LogLevel = m.logLevels.nacosLevel()
+	}
+	return vo.NacosClientParam{
+		ServerConfigs: m.serverConfigs,
+		ClientConfig:  &clientConfig,
+	}
+}
+
+// 创建默认namespace的底层sdk实例
+func (m *Manager) start(config *NacosConfig) error {
+	if !config.DisableConfig {
+		cc, err := clients.NewConfigClient(m.clientParam(m.namespace))
+		if err != nil {
+			return err
+		}
+		m.configInstance = cc
+		m.configInstances[m.namespace] = cc
+	}
+	if !config.DisableDiscovery {
+		nc, err := clients.NewNamingClient(m.clientParam(m.namespace))
+		if err != nil {
+			return err
+		}
+		m.namingInstance = nc
+		m.namingInstances[m.namespace] = nc
+	}
+	return nil
+}
+
+// 注销所有已注册的实例并关闭全部底层sdk实例 返回是否在maxWaitTime内完成
+func (m *Manager) stop(maxWaitTime time.Duration) bool {
+	close(m.done)
+	if m.logLevels != nil {
+		m.logLevels.restore()
+	}
+	m.configLocker.Lock()
+	for _, v := range m.configInstances {
+		v.CloseClient()
+	}
+	m.configLocker.Unlock()
+	if m.namingInstance == nil {
+		return true
+	}
+	done := make(chan interface{}, 1)
+	go func() {
+		m.namingLocker.Lock()
+		defer m.namingLocker.Unlock()
+		for _, v := range m.namingClient {
+			for id, i := range v.registered {
+				flag, err := v.Unregister(id)
+				if err != nil {
+					logger.Logrus().WithError(err).Error("unregister instance failed ip:", i.Ip, "port:", i.Port)
+				} else {
+					logger.Logrus().Traceln("unregister instance ip:", i.Ip, "port:", i.Port, "result:", flag)
+				}
+			}
+		}
+		for _, v := range m.namingInstances {
+			v.CloseClient()
+		}
+		done <- true
+	}()
+	select {
+	case <-done:
+		return true
+	case <-time.After(maxWaitTime):
+		return false
+	}
+}
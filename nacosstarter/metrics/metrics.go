@@ -0,0 +1,225 @@
+// Package metrics 将nacosstarter的配置与服务发现活动导出为Prometheus指标
+//
+// 使用方式:
+//
+//	collector, err := metrics.Register(prometheus.DefaultRegisterer)
+//	starter := &nacosstarter.NacosStarter{Config: nacosstarter.NacosConfig{Observer: collector, ...}}
+package metrics
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/golang-acexy/starter-nacos/nacosstarter"
+	"github.com/nacos-group/nacos-sdk-go/v2/model"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// 指标名称前缀
+const metricNamespace = "nacos"
+
+const (
+	resultSuccess     = "success"
+	resultError       = "error"
+	resultDecodeError = "decode_error"
+	resultNotFound    = "not_found"
+)
+
+// Collector 实现nacosstarter.Observer的Prometheus指标收集器
+type Collector struct {
+	nacosstarter.NopObserver
+
+	configApplies     *prometheus.CounterVec
+	configLastSuccess *prometheus.GaugeVec
+	configWatches     *prometheus.GaugeVec
+
+	namingOperations *prometheus.CounterVec
+	serviceInstances *prometheus.GaugeVec
+	serviceHealthy   *prometheus.GaugeVec
+	selectDuration   *prometheus.HistogramVec
+
+	// 各服务的订阅者数量 全部取消订阅后删除实例数指标
+	mu          sync.Mutex
+	subscribers map[serviceKey]int
+}
+
+type serviceKey struct {
+	namespace, group, service string
+}
+
+// New 创建指标收集器 需要自行注册到Registerer
+func New() *Collector {
+	configLabels := []string{"namespace", "group", "data_id"}
+	serviceLabels := []string{"namespace", "group", "service"}
+	return &Collector{
+		configApplies: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricNamespace,
+			Subsystem: "config",
+			Name:      "applies_total",
+			Help:      "Config loads and reloads by kind (load, reload) and result (success, decode_error, not_found, error).",
+		}, append(configLabels, "kind", "result")),
+		configLastSuccess: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: metricNamespace,
+			Subsystem: "config",
+			Name:      "last_success_timestamp_seconds",
+			Help:      "Unix time of the last successful config load or reload.",
+		}, configLabels),
+		configWatches: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: metricNamespace,
+			Subsystem: "config",
+			Name:      "watches",
+			Help:      "Active config watch subscribers.",
+		}, configLabels),
+		namingOperations: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricNamespace,
+			Subsystem: "naming",
+			Name:      "operations_total",
+			Help:      "Instance register and deregister calls by operation and result (success, error).",
+		}, append(serviceLabels, "operation", "result")),
+		serviceInstances: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: metricNamespace,
+			Subsystem: "naming",
+			Name:      "service_instances",
+			Help:      "Instances of subscribed services as of the last notification.",
+		}, serviceLabels),
+		serviceHealthy: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: metricNamespace,
+			Subsystem: "naming",
+			Name:      "service_healthy_instances",
+			Help:      "Healthy and enabled instances of subscribed services as of the last notification.",
+		}, serviceLabels),
+		selectDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: metricNamespace,
+			Subsystem: "naming",
+			Name:      "select_duration_seconds",
+			Help:      "Latency of instance selection by result (success, error).",
+			Buckets:   prometheus.DefBuckets,
+		}, append(serviceLabels, "result")),
+		subscribers: make(map[serviceKey]int),
+	}
+}
+
+// Register 创建指标收集器并注册到reg reg为nil时使用prometheus.DefaultRegisterer
+func Register(reg prometheus.Registerer) (*Collector, error) {
+	if reg == nil {
+		reg = prometheus.DefaultRegisterer
+	}
+	c := New()
+	if err := reg.Register(c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *Collector) collectors() []prometheus.Collector {
+	return []prometheus.Collector{
+		c.configApplies, c.configLastSuccess, c.configWatches,
+		c.namingOperations, c.serviceInstances, c.serviceHealthy, c.selectDuration,
+	}
+}
+
+// Describe 实现prometheus.Collector
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	for _, v := range c.collectors() {
+		v.Describe(ch)
+	}
+}
+
+// Collect 实现prometheus.Collector
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	for _, v := range c.collectors() {
+		v.Collect(ch)
+	}
+}
+
+func (c *Collector) ConfigApplied(namespace, group, dataId string, reload bool, err error) {
+	kind := "load"
+	if reload {
+		kind = "reload"
+	}
+	namespace = namespaceLabel(namespace)
+	c.configApplies.WithLabelValues(namespace, group, dataId, kind, configResult(err)).Inc()
+	if err == nil {
+		c.configLastSuccess.WithLabelValues(namespace, group, dataId).SetToCurrentTime()
+	}
+}
+
+func (c *Collector) ConfigWatchChanged(namespace, group, dataId string, delta int) {
+	c.configWatches.WithLabelValues(namespaceLabel(namespace), group, dataId).Add(float64(delta))
+}
+
+func (c *Collector) InstanceRegistered(namespace, group, serviceName string, ok bool) {
+	c.namingOperations.WithLabelValues(namespaceLabel(namespace), group, serviceName, "register", okResult(ok)).Inc()
+}
+
+func (c *Collector) InstanceDeregistered(namespace, group, serviceName string, ok bool) {
+	c.namingOperations.WithLabelValues(namespaceLabel(namespace), group, serviceName, "deregister", okResult(ok)).Inc()
+}
+
+func (c *Collector) ServiceInstancesChanged(namespace, group, serviceName string, instances []model.Instance) {
+	healthy := 0
+	for _, v := range instances {
+		if v.Healthy && v.Enable {
+			healthy++
+		}
+	}
+	namespace = namespaceLabel(namespace)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// 取消订阅后送达的通知不再重建指标
+	if c.subscribers[serviceKey{namespace, group, serviceName}] == 0 {
+		return
+	}
+	c.serviceInstances.WithLabelValues(namespace, group, serviceName).Set(float64(len(instances)))
+	c.serviceHealthy.WithLabelValues(namespace, group, serviceName).Set(float64(healthy))
+}
+
+func (c *Collector) ServiceWatchChanged(namespace, group, serviceName string, delta int) {
+	namespace = namespaceLabel(namespace)
+	key := serviceKey{namespace, group, serviceName}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.subscribers[key] += delta
+	if c.subscribers[key] > 0 {
+		return
+	}
+	delete(c.subscribers, key)
+	c.serviceInstances.DeleteLabelValues(namespace, group, serviceName)
+	c.serviceHealthy.DeleteLabelValues(namespace, group, serviceName)
+}
+
+func (c *Collector) InstancesSelected(namespace, group, serviceName string, elapsed time.Duration, err error) {
+	result := resultSuccess
+	if err != nil {
+		result = resultError
+	}
+	c.selectDuration.WithLabelValues(namespaceLabel(namespace), group, serviceName, result).Observe(elapsed.Seconds())
+}
+
+// 空namespace即public
+func namespaceLabel(namespace string) string {
+	if namespace == "" {
+		return "public"
+	}
+	return namespace
+}
+
+func configResult(err error) string {
+	switch {
+	case err == nil:
+		return resultSuccess
+	case errors.Is(err, nacosstarter.ErrDecode):
+		return resultDecodeError
+	case errors.Is(err, nacosstarter.ErrConfigNotFound):
+		return resultNotFound
+	}
+	return resultError
+}
+
+func okResult(ok bool) string {
+	if ok {
+		return resultSuccess
+	}
+	return resultError
+}
@@ -0,0 +1,30 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/nacos-group/nacos-sdk-go/v2/model"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestServiceGaugesDeletedAfterUnsubscribe(t *testing.T) {
+	c := New()
+	instances := []model.Instance{{Healthy: true, Enable: true}, {Healthy: false, Enable: true}}
+	c.ServiceWatchChanged("", "DEFAULT_GROUP", "order", 1)
+	c.ServiceWatchChanged("", "DEFAULT_GROUP", "order", 1)
+	c.ServiceInstancesChanged("", "DEFAULT_GROUP", "order", instances)
+	if got := testutil.ToFloat64(c.serviceHealthy.WithLabelValues("public", "DEFAULT_GROUP", "order")); got != 1 {
+		t.Fatalf("healthy = %v", got)
+	}
+	// 仍有其他订阅者时保留指标
+	c.ServiceWatchChanged("", "DEFAULT_GROUP", "order", -1)
+	if n := testutil.CollectAndCount(c.serviceInstances); n != 1 {
+		t.Fatalf("series = %d", n)
+	}
+	c.ServiceWatchChanged("", "DEFAULT_GROUP", "order", -1)
+	// 取消订阅后送达的通知不再重建指标
+	c.ServiceInstancesChanged("", "DEFAULT_GROUP", "order", instances)
+	if n := testutil.CollectAndCount(c.serviceInstances) + testutil.CollectAndCount(c.serviceHealthy); n != 0 {
+		t.Errorf("series = %d after unsubscribe", n)
+	}
+}
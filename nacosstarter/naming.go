@@ -1,7 +1,10 @@
 package nacosstarter
 
 import (
-	"errors"
+	"context"
+	"fmt"
+	"strings"
+	"time"
 
 	"github.com/acexy/golang-toolkit/crypto/hashing"
 	"github.com/acexy/golang-toolkit/logger"
@@ -14,7 +17,14 @@ import (
 
 // Register 向注册中心注册临时实例
 // 一个NamingClient只能注册一个实例，重复注册将出现替换
+// 参数无效时返回的错误可以匹配ErrInvalidParam，请求失败时可以匹配ErrServerUnavailable
 func (n *NamingClient) Register(instance Instance) (string, error) {
+	if instance.ServiceName == "" {
+		return "", invalidParam("serviceName")
+	}
+	if instance.Ip == "" {
+		return "", invalidParam("ip")
+	}
 	var err error
 	var flag bool
 	var id string
@@ -29,18 +39,22 @@ func (n *NamingClient) Register(instance Instance) (string, error) {
 		GroupName:   n.group,
 		Ephemeral:   true,
 	}
-	flag, err = namingInstance.RegisterInstance(param)
+	flag, err = n.client.RegisterInstance(param)
+	n.m.observer.InstanceRegistered(n.namespace, n.group, param.ServiceName, err == nil && flag)
 	if err == nil && flag {
 		logger.Logrus().Traceln("registered ip", param.Ip, "port", param.Port, "service", param.ServiceName)
 		id = hashing.Md5Hex(param.Ip + conversion.FromUint64(param.Port))
 		n.registered[id] = param
 	}
-	return id, err
+	return id, serverError(err)
 }
 
 func (n *NamingClient) RegisterBatch(serviceName string, instances []InstanceBatch) ([]string, error) {
+	if serviceName == "" {
+		return nil, invalidParam("serviceName")
+	}
 	if len(instances) == 0 {
-		return nil, errors.New("empty instance")
+		return nil, invalidParam("instance")
 	}
 	var err error
 	var flag bool
@@ -65,21 +79,22 @@ func (n *NamingClient) RegisterBatch(serviceName string, instances []InstanceBat
 	param.GroupName = n.group
 	param.ServiceName = serviceName
 
-	flag, err = namingInstance.BatchRegisterInstance(param)
+	flag, err = n.client.BatchRegisterInstance(param)
+	n.m.observer.InstanceRegistered(n.namespace, n.group, serviceName, err == nil && flag)
 	if err == nil && flag {
 		for i, v := range ids {
 			n.registered[v] = instanceParam[i]
 		}
-		return ids, err
+		return ids, nil
 	}
-	return nil, err
+	return nil, serverError(err)
 }
 
 // Unregister 向注册中心注销实例
 func (n *NamingClient) Unregister(instanceId string) (bool, error) {
 	v, ok := n.registered[instanceId]
 	if !ok {
-		return false, errors.New("bad instanceId")
+		return false, fmt.Errorf("%w %s", ErrUnknownInstance, instanceId)
 	}
 	param := vo.DeregisterInstanceParam{
 		Ip:          v.Ip,
@@ -89,9 +104,10 @@ func (n *NamingClient) Unregister(instanceId string) (bool, error) {
 		GroupName:   v.GroupName,
 		Ephemeral:   v.Ephemeral,
 	}
-	flag, err := namingInstance.DeregisterInstance(param)
+	flag, err := n.client.DeregisterInstance(param)
+	n.m.observer.InstanceDeregistered(n.namespace, n.group, param.ServiceName, err == nil && flag)
 	if err != nil {
-		return false, err
+		return false, serverError(err)
 	}
 	if !flag {
 		return false, nil
@@ -102,25 +118,36 @@ func (n *NamingClient) Unregister(instanceId string) (bool, error) {
 
 // GetService 获取指定服务的概要信息
 func (n *NamingClient) GetService(serviceName string) (model.Service, error) {
-	return namingInstance.GetService(vo.GetServiceParam{
+	if serviceName == "" {
+		return model.Service{}, invalidParam("serviceName")
+	}
+	service, err := n.client.GetService(vo.GetServiceParam{
 		ServiceName: serviceName,
 		GroupName:   n.group,
 	})
+	return service, serverError(err)
 }
 
 // GetServicePage 获取指定服务的注册信息
 func (n *NamingClient) GetServicePage(pageNo, pageSize uint) (model.ServiceList, error) {
-	return namingInstance.GetAllServicesInfo(vo.GetAllServiceInfoParam{
-		NameSpace: namespace,
+	services, err := n.client.GetAllServicesInfo(vo.GetAllServiceInfoParam{
+		NameSpace: n.namespace,
 		GroupName: n.group,
 		PageNo:    uint32(pageNo),
 		PageSize:  uint32(pageSize),
 	})
+	return services, serverError(err)
 }
 
 // GetAllInstances 获取指定服务的所有实例(不论当前是否可用)
 func (n *NamingClient) GetAllInstances(serviceName string) ([]RegisteredInstance, error) {
-	instances, err := namingInstance.SelectAllInstances(vo.SelectAllInstancesParam{ServiceName: serviceName, GroupName: n.group})
+	if serviceName == "" {
+		return nil, invalidParam("serviceName")
+	}
+	start := time.Now()
+	instances, err := n.client.SelectAllInstances(vo.SelectAllInstancesParam{ServiceName: serviceName, GroupName: n.group})
+	err = serverError(err)
+	n.observeSelected(serviceName, start, err)
 	if err != nil {
 		return nil, err
 	}
@@ -133,7 +160,13 @@ func (n *NamingClient) GetAllInstances(serviceName string) ([]RegisteredInstance
 
 // GetHealthyInstances 获取指定服务的可用实例
 func (n *NamingClient) GetHealthyInstances(serviceName string) ([]RegisteredInstance, error) {
-	instances, err := namingInstance.SelectInstances(vo.SelectInstancesParam{ServiceName: serviceName, GroupName: n.group, HealthyOnly: true})
+	if serviceName == "" {
+		return nil, invalidParam("serviceName")
+	}
+	start := time.Now()
+	instances, err := n.client.SelectInstances(vo.SelectInstancesParam{ServiceName: serviceName, GroupName: n.group, HealthyOnly: true})
+	err = serverError(err)
+	n.observeSelected(serviceName, start, err)
 	if err != nil {
 		return nil, err
 	}
@@ -144,9 +177,21 @@ func (n *NamingClient) GetHealthyInstances(serviceName string) ([]RegisteredInst
 	return result, nil
 }
 
-// ChooseOneHealthyInstance 选择一个可用的实例
+// ChooseOneHealthyInstance 选择一个可用的实例 没有可用实例时返回的错误可以匹配ErrNoInstance
 func (n *NamingClient) ChooseOneHealthyInstance(serviceName string) (*RegisteredInstance, error) {
-	instance, err := namingInstance.SelectOneHealthyInstance(vo.SelectOneHealthInstanceParam{ServiceName: serviceName, GroupName: n.group})
+	if serviceName == "" {
+		return nil, invalidParam("serviceName")
+	}
+	start := time.Now()
+	instance, err := n.client.SelectOneHealthyInstance(vo.SelectOneHealthInstanceParam{ServiceName: serviceName, GroupName: n.group})
+	switch {
+	case err == nil:
+	case isEmptyInstanceList(err):
+		err = fmt.Errorf("%w: service %s: %w", ErrNoInstance, serviceName, err)
+	default:
+		err = serverError(err)
+	}
+	n.observeSelected(serviceName, start, err)
 	if err != nil {
 		return nil, err
 	}
@@ -156,17 +201,22 @@ func (n *NamingClient) ChooseOneHealthyInstance(serviceName string) (*Registered
 // WatchNaming 监控服务的实例变化
 // * 如果UpdateCacheWhenEmpty=false 当前服务只有一个实例时，不会触发监听
 func (n *NamingClient) WatchNaming(serviceName string, watch func(instance []model.Instance, err error)) (string, error) {
+	if serviceName == "" {
+		return "", invalidParam("serviceName")
+	}
 	n.mu.Lock()
 	defer n.mu.Unlock()
 	param := &vo.SubscribeParam{ServiceName: serviceName, GroupName: n.group}
 	watchId := hashing.Md5Hex(serviceName)
 	_, ok := n.watched[watchId]
 	if ok {
-		return watchId, errors.New("duplicated watchId")
+		return watchId, fmt.Errorf("%w: service %s", ErrDuplicateWatch, serviceName)
 	}
-	param.SubscribeCallback = watch
+	param.SubscribeCallback = n.observeSubscribe(serviceName, watch)
 	n.watched[watchId] = param
-	return watchId, namingInstance.Subscribe(param)
+	// sdk在订阅请求前注册回调 失败时回调同样保留，直到取消订阅
+	n.observeWatch(serviceName, 1)
+	return watchId, serverError(n.client.Subscribe(param))
 }
 
 // UnwatchNaming 取消监控服务实例变化
@@ -175,11 +225,54 @@ func (n *NamingClient) UnwatchNaming(watchId string) error {
 	defer n.mu.Unlock()
 	v, ok := n.watched[watchId]
 	if !ok {
-		return errors.New("bad watchId")
+		return fmt.Errorf("%w %s", ErrUnknownWatch, watchId)
 	}
-	err := namingInstance.Unsubscribe(v)
-	if err != nil {
+	err := n.client.Unsubscribe(v)
+	if err == nil {
 		delete(n.watched, watchId)
+		n.observeWatch(v.ServiceName, -1)
+	}
+	return serverError(err)
+}
+
+// NamingEvent 服务实例变化事件
+type NamingEvent struct {
+	ServiceName string
+	Instances   []model.Instance
+	Err         error
+}
+
+// Watch 以channel的形式监控服务的实例变化
+// ctx结束后将自动取消订阅并关闭channel，同一服务可以同时存在多个订阅
+// 消费方读取不及时时丢弃较早的事件，事件顺序不保证与变更顺序一致
+func (n *NamingClient) Watch(ctx context.Context, serviceName string) (<-chan NamingEvent, error) {
+	if serviceName == "" {
+		return nil, invalidParam("serviceName")
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	events := newEventChannel[NamingEvent](ctx)
+	param := &vo.SubscribeParam{ServiceName: serviceName, GroupName: n.group}
+	param.SubscribeCallback = n.observeSubscribe(serviceName, func(instances []model.Instance, err error) {
+		events.send(NamingEvent{ServiceName: serviceName, Instances: instances, Err: err})
+	})
+	n.observeWatch(serviceName, 1)
+	if err := n.client.Subscribe(param); err != nil {
+		n.observeWatch(serviceName, -1)
+		return nil, serverError(err)
 	}
-	return err
+	events.closeOnDone(func() {
+		if err := n.client.Unsubscribe(param); err != nil {
+			logger.Logrus().WithError(err).Warnln("cant unsubscribe service:", serviceName, "group:", n.group)
+			return
+		}
+		n.observeWatch(serviceName, -1)
+	})
+	return events.ch, nil
+}
+
+// sdk在服务没有可用实例时返回 "instance list is empty!" 或 "healthy instance list is empty!"
+func isEmptyInstanceList(err error) bool {
+	return strings.Contains(err.Error(), "instance list is empty")
 }
@@ -0,0 +1,134 @@
+package nacosstarter
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/nacos-group/nacos-sdk-go/v2/clients/naming_client"
+	"github.com/nacos-group/nacos-sdk-go/v2/model"
+	"github.com/nacos-group/nacos-sdk-go/v2/vo"
+)
+
+// 不依赖服务端的服务发现sdk 所有请求返回err
+type fakeNamingClient struct {
+	naming_client.INamingClient
+	err error
+	// 非空时SelectOneHealthyInstance返回该错误
+	selectErr error
+}
+
+func (f *fakeNamingClient) RegisterInstance(param vo.RegisterInstanceParam) (bool, error) {
+	return f.err == nil, f.err
+}
+
+func (f *fakeNamingClient) BatchRegisterInstance(param vo.BatchRegisterInstanceParam) (bool, error) {
+	return f.err == nil, f.err
+}
+
+func (f *fakeNamingClient) GetService(param vo.GetServiceParam) (model.Service, error) {
+	return model.Service{}, f.err
+}
+
+func (f *fakeNamingClient) GetAllServicesInfo(param vo.GetAllServiceInfoParam) (model.ServiceList, error) {
+	return model.ServiceList{}, f.err
+}
+
+func (f *fakeNamingClient) SelectAllInstances(param vo.SelectAllInstancesParam) ([]model.Instance, error) {
+	return nil, f.err
+}
+
+func (f *fakeNamingClient) SelectInstances(param vo.SelectInstancesParam) ([]model.Instance, error) {
+	return nil, f.err
+}
+
+func (f *fakeNamingClient) SelectOneHealthyInstance(param vo.SelectOneHealthInstanceParam) (*model.Instance, error) {
+	if f.selectErr != nil {
+		return nil, f.selectErr
+	}
+	return &model.Instance{Ip: "127.0.0.1", Port: 80}, f.err
+}
+
+func (f *fakeNamingClient) Subscribe(param *vo.SubscribeParam) error {
+	return f.err
+}
+
+func newTestNamingClient(client naming_client.INamingClient) *NamingClient {
+	m := &Manager{observer: NopObserver{}, done: make(chan struct{})}
+	return &NamingClient{m: m, group: "DEFAULT_GROUP", client: client, registered: make(map[string]vo.RegisterInstanceParam), watched: make(map[string]*vo.SubscribeParam)}
+}
+
+func TestNamingErrors(t *testing.T) {
+	n := newTestNamingClient(&fakeNamingClient{err: errors.New("request timeout")})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	calls := map[string]func(serviceName string) error{
+		"Register": func(s string) error {
+			_, err := n.Register(Instance{Ip: "127.0.0.1", ServiceName: s, Port: 80})
+			return err
+		},
+		"RegisterBatch": func(s string) error {
+			_, err := n.RegisterBatch(s, []InstanceBatch{{Ip: "127.0.0.1", Port: 80}})
+			return err
+		},
+		"GetService": func(s string) error {
+			_, err := n.GetService(s)
+			return err
+		},
+		"GetAllInstances": func(s string) error {
+			_, err := n.GetAllInstances(s)
+			return err
+		},
+		"GetHealthyInstances": func(s string) error {
+			_, err := n.GetHealthyInstances(s)
+			return err
+		},
+		"ChooseOneHealthyInstance": func(s string) error {
+			_, err := n.ChooseOneHealthyInstance(s)
+			return err
+		},
+		"WatchNaming": func(s string) error {
+			_, err := n.WatchNaming(s, func([]model.Instance, error) {})
+			return err
+		},
+		"Watch": func(s string) error {
+			_, err := n.Watch(ctx, s)
+			return err
+		},
+	}
+	for name, call := range calls {
+		if err := call(""); !errors.Is(err, ErrInvalidParam) {
+			t.Errorf("%s with empty service: err = %v", name, err)
+		}
+		if err := call("order"); !errors.Is(err, ErrServerUnavailable) {
+			t.Errorf("%s: err = %v", name, err)
+		}
+	}
+	if _, err := n.GetServicePage(1, 10); !errors.Is(err, ErrServerUnavailable) {
+		t.Errorf("GetServicePage: err = %v", err)
+	}
+	if _, err := n.RegisterBatch("order", nil); !errors.Is(err, ErrInvalidParam) {
+		t.Errorf("RegisterBatch without instances: err = %v", err)
+	}
+	if _, err := n.Unregister("unknown"); !errors.Is(err, ErrUnknownInstance) {
+		t.Errorf("Unregister: err = %v", err)
+	}
+}
+
+func TestChooseOneHealthyInstanceEmpty(t *testing.T) {
+	n := newTestNamingClient(&fakeNamingClient{selectErr: errors.New("healthy instance list is empty!")})
+	_, err := n.ChooseOneHealthyInstance("order")
+	if !errors.Is(err, ErrNoInstance) || errors.Is(err, ErrServerUnavailable) {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func TestWatchNamingDuplicated(t *testing.T) {
+	n := newTestNamingClient(&fakeNamingClient{})
+	if _, err := n.WatchNaming("order", func([]model.Instance, error) {}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := n.WatchNaming("order", func([]model.Instance, error) {}); !errors.Is(err, ErrDuplicateWatch) {
+		t.Errorf("err = %v", err)
+	}
+}
@@ -0,0 +1,193 @@
+package nacosstarter
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// 将配置解析为yaml节点树 标量保留原文，避免数字精度、日期格式在重新编码时发生变化
+// yaml与json直接解析，其他格式先反序列化为通用树结构再转换; 空文档返回nil
+func documentNode(content string, configType ConfigType) (*yaml.Node, error) {
+	var node *yaml.Node
+	switch configType {
+	case ConfigTypeYaml:
+		var doc yaml.Node
+		if err := yaml.Unmarshal([]byte(content), &doc); err != nil {
+			return nil, newDecodeError(content, configType, err)
+		}
+		if len(doc.Content) > 0 {
+			node = resolveAlias(doc.Content[0])
+		}
+	case ConfigTypeJson:
+		decoder := json.NewDecoder(strings.NewReader(content))
+		decoder.UseNumber()
+		var tree any
+		if err := decoder.Decode(&tree); err != nil {
+			return nil, newDecodeError(content, configType, err)
+		}
+		if _, err := decoder.Token(); err != io.EOF {
+			return nil, newDecodeError(content, configType, errors.New("invalid character after top-level value"))
+		}
+		node = jsonNode(tree)
+	default:
+		// 不经过解密 明文不会出现在重新编码的内容中
+		c, ok := lookupCodec(configType)
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown config type %s", ErrInvalidParam, configType)
+		}
+		var tree map[string]any
+		if err := c.decoder(content, &tree); err != nil {
+			return nil, newDecodeError(content, configType, err)
+		}
+		node = new(yaml.Node)
+		if err := node.Encode(tree); err != nil {
+			return nil, err
+		}
+		// 来自其他格式的字符串值保持字符串类型
+		_ = walkValues(node, func(n *yaml.Node) error {
+			if n.ShortTag() == "!!str" {
+				n.Style = yaml.DoubleQuotedStyle
+			}
+			return nil
+		})
+	}
+	if node != nil && node.ShortTag() == "!!null" {
+		return nil, nil
+	}
+	return node, nil
+}
+
+// 复制节点并展开别名 合并后锚点可能已被其他层覆盖
+func resolveAlias(node *yaml.Node) *yaml.Node {
+	if node.Kind == yaml.AliasNode && node.Alias != nil {
+		return resolveAlias(node.Alias)
+	}
+	n := *node
+	n.Anchor = ""
+	n.Content = make([]*yaml.Node, len(node.Content))
+	for i, child := range node.Content {
+		n.Content[i] = resolveAlias(child)
+	}
+	return &n
+}
+
+func jsonNode(v any) *yaml.Node {
+	switch v := v.(type) {
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		n := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		for _, k := range keys {
+			n.Content = append(n.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: k}, jsonNode(v[k]))
+		}
+		return n
+	case []any:
+		n := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		for _, item := range v {
+			n.Content = append(n.Content, jsonNode(item))
+		}
+		return n
+	case json.Number:
+		tag := "!!int"
+		if strings.ContainsAny(v.String(), ".eE") {
+			tag = "!!float"
+		}
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: tag, Value: v.String()}
+	case string:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: v, Style: yaml.DoubleQuotedStyle}
+	case bool:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: strconv.FormatBool(v)}
+	}
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Value: "null"}
+}
+
+// 将节点树编码为目标格式
+// yaml与json按原文输出标量，其他格式经由通用树结构编码
+func encodeNode(node *yaml.Node, configType ConfigType) (string, error) {
+	if node == nil {
+		node = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	}
+	switch configType {
+	case ConfigTypeYaml:
+		b, err := yaml.Marshal(node)
+		return string(b), err
+	case ConfigTypeJson:
+		var b strings.Builder
+		err := writeJsonNode(&b, node)
+		return b.String(), err
+	}
+	var tree map[string]any
+	if err := node.Decode(&tree); err != nil {
+		return "", err
+	}
+	return serializeConfig(configType, tree)
+}
+
+var jsonNumberPattern = regexp.MustCompile(`^-?(0|[1-9][0-9]*)(\.[0-9]+)?([eE][+-]?[0-9]+)?$`)
+
+func writeJsonNode(b *strings.Builder, node *yaml.Node) error {
+	switch node.Kind {
+	case yaml.MappingNode:
+		b.WriteByte('{')
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			key, _ := json.Marshal(node.Content[i].Value)
+			b.Write(key)
+			b.WriteByte(':')
+			if err := writeJsonNode(b, node.Content[i+1]); err != nil {
+				return err
+			}
+		}
+		b.WriteByte('}')
+		return nil
+	case yaml.SequenceNode:
+		b.WriteByte('[')
+		for i, item := range node.Content {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			if err := writeJsonNode(b, item); err != nil {
+				return err
+			}
+		}
+		b.WriteByte(']')
+		return nil
+	case yaml.AliasNode:
+		return writeJsonNode(b, node.Alias)
+	}
+	switch node.ShortTag() {
+	case "!!int", "!!float":
+		// 原文本身是合法的json数字时直接输出 保留精度与格式
+		if jsonNumberPattern.MatchString(node.Value) {
+			b.WriteString(node.Value)
+			return nil
+		}
+	case "!!str", "!!timestamp", "!!binary":
+		value, _ := json.Marshal(node.Value)
+		b.Write(value)
+		return nil
+	}
+	var v any
+	if err := node.Decode(&v); err != nil {
+		return err
+	}
+	value, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	b.Write(value)
+	return nil
+}
@@ -0,0 +1,70 @@
+package nacosstarter
+
+import (
+	"time"
+
+	"github.com/nacos-group/nacos-sdk-go/v2/model"
+)
+
+// Observer 配置与服务发现活动的观察者 用于对接监控指标，参考子包metrics
+// 回调在对应操作所在的goroutine中同步执行，实现方应尽快返回且保证并发安全
+// 嵌入NopObserver后只需实现关心的方法
+type Observer interface {
+	// ConfigApplied 配置加载(reload=false)或热更新(reload=true)完成 err为nil表示成功
+	// 反序列化失败的err可以匹配ErrDecode，内容与上一次应用相同而被忽略的变更通知不会上报
+	ConfigApplied(namespace, group, dataId string, reload bool, err error)
+	// ConfigWatchChanged 配置的订阅者数量变化 新增订阅时delta为1，取消订阅时为-1
+	ConfigWatchChanged(namespace, group, dataId string, delta int)
+	// InstanceRegistered 注册实例完成 批量注册时每次调用通知一次
+	InstanceRegistered(namespace, group, serviceName string, ok bool)
+	// InstanceDeregistered 注销实例完成
+	InstanceDeregistered(namespace, group, serviceName string, ok bool)
+	// ServiceInstancesChanged 已订阅服务的实例列表发生变化
+	// 取消订阅后sdk仍可能送达正在执行的通知
+	ServiceInstancesChanged(namespace, group, serviceName string, instances []model.Instance)
+	// ServiceWatchChanged 服务的订阅者数量变化 新增订阅时delta为1，取消订阅时为-1
+	ServiceWatchChanged(namespace, group, serviceName string, delta int)
+	// InstancesSelected 查询服务实例完成 elapsed为本次查询耗时
+	InstancesSelected(namespace, group, serviceName string, elapsed time.Duration, err error)
+}
+
+// NopObserver 不做任何处理的Observer
+type NopObserver struct{}
+
+func (NopObserver) ConfigApplied(namespace, group, dataId string, reload bool, err error) {}
+
+func (NopObserver) ConfigWatchChanged(namespace, group, dataId string, delta int) {}
+
+func (NopObserver) InstanceRegistered(namespace, group, serviceName string, ok bool) {}
+
+func (NopObserver) InstanceDeregistered(namespace, group, serviceName string, ok bool) {}
+
+func (NopObserver) ServiceInstancesChanged(namespace, group, serviceName string, instances []model.Instance) {
+}
+
+func (NopObserver) ServiceWatchChanged(namespace, group, serviceName string, delta int) {}
+
+func (NopObserver) InstancesSelected(namespace, group, serviceName string, elapsed time.Duration, err error) {
+}
+
+func (c *ConfigClient) observeApplied(dataId string, reload bool, err error) {
+	c.m.observer.ConfigApplied(c.namespace, c.group, dataId, reload, err)
+}
+
+// 包装订阅回调 在通知调用方前上报实例变化
+func (n *NamingClient) observeSubscribe(serviceName string, callback func(instances []model.Instance, err error)) func(instances []model.Instance, err error) {
+	return func(instances []model.Instance, err error) {
+		if err == nil {
+			n.m.observer.ServiceInstancesChanged(n.namespace, n.group, serviceName, instances)
+		}
+		callback(instances, err)
+	}
+}
+
+func (n *NamingClient) observeWatch(serviceName string, delta int) {
+	n.m.observer.ServiceWatchChanged(n.namespace, n.group, serviceName, delta)
+}
+
+func (n *NamingClient) observeSelected(serviceName string, start time.Time, err error) {
+	n.m.observer.InstancesSelected(n.namespace, n.group, serviceName, time.Since(start), err)
+}
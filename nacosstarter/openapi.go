@@ -0,0 +1,161 @@
+package nacosstarter
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/acexy/golang-toolkit/util/json"
+	"github.com/nacos-group/nacos-sdk-go/v2/common/constant"
+)
+
+const defaultOpenApiTimeout = 10 * time.Second
+
+// Nacos的http开放接口 用于sdk未提供的功能
+// 按顺序尝试每个服务端，开启鉴权时自动登录并缓存accessToken
+type openApi struct {
+	m      *Manager
+	client http.Client
+
+	mu          sync.Mutex
+	token       string
+	tokenExpire time.Time
+}
+
+// 开放接口的统一返回结构
+type restResult[T any] struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Data    T      `json:"data"`
+}
+
+// 服务端处理失败时同样可能返回200状态码
+func (r restResult[T]) err() error {
+	if r.Code != 0 && r.Code != http.StatusOK {
+		return fmt.Errorf("nacos open api failed code %d: %s", r.Code, r.Message)
+	}
+	return nil
+}
+
+type loginResult struct {
+	AccessToken string `json:"accessToken"`
+	// token有效期 秒
+	TokenTtl int64 `json:"tokenTtl"`
+}
+
+func newOpenApi(m *Manager) *openApi {
+	timeout := time.Duration(m.clientConfig.TimeoutMs) * time.Millisecond
+	if timeout <= 0 {
+		timeout = defaultOpenApiTimeout
+	}
+	return &openApi{m: m, client: http.Client{Timeout: timeout}}
+}
+
+// 请求开放接口并解析返回内容 path不包含contextPath，如 /v1/cs/configs
+func (a *openApi) request(method, path string, params url.Values, result any) error {
+	var lastErr error
+	for _, server := range a.m.serverConfigs {
+		base := serverBaseUrl(server)
+		query := url.Values{}
+		for k, v := range params {
+			query[k] = v
+		}
+		token, err := a.accessToken(base)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if token != "" {
+			query.Set("accessToken", token)
+		}
+		body, status, err := a.do(method, base+path, query)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if status < 200 || status >= 300 {
+			return fmt.Errorf("%w: %s %s status %d: %s", ErrServerUnavailable, method, path, status, strings.TrimSpace(string(body)))
+		}
+		// 查询的数据不存在时部分接口返回空内容
+		if result == nil || len(bytes.TrimSpace(body)) == 0 {
+			return nil
+		}
+		return json.ParseBytesError(body, result)
+	}
+	if lastErr == nil {
+		lastErr = fmt.Errorf("no server config")
+	}
+	return fmt.Errorf("%w: %w", ErrServerUnavailable, lastErr)
+}
+
+func (a *openApi) do(method, rawUrl string, query url.Values) ([]byte, int, error) {
+	var req *http.Request
+	var err error
+	if method == http.MethodPost {
+		req, err = http.NewRequest(method, rawUrl, strings.NewReader(query.Encode()))
+		if err == nil {
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		}
+	} else {
+		req, err = http.NewRequest(method, rawUrl+"?"+query.Encode(), nil)
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	return body, resp.StatusCode, err
+}
+
+// 未配置用户名时不需要鉴权
+func (a *openApi) accessToken(base string) (string, error) {
+	config := a.m.clientConfig
+	if config.Username == "" {
+		return "", nil
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.token != "" && time.Now().Before(a.tokenExpire) {
+		return a.token, nil
+	}
+	form := url.Values{}
+	form.Set("username", config.Username)
+	form.Set("password", config.Password)
+	body, status, err := a.do(http.MethodPost, base+"/v1/auth/login", form)
+	if err != nil {
+		return "", err
+	}
+	if status != http.StatusOK {
+		return "", fmt.Errorf("nacos login failed status %d: %s", status, strings.TrimSpace(string(body)))
+	}
+	var login loginResult
+	if err = json.ParseBytesError(body, &login); err != nil {
+		return "", err
+	}
+	a.token = login.AccessToken
+	// 提前刷新 避免请求过程中过期
+	a.tokenExpire = time.Now().Add(time.Duration(login.TokenTtl) * time.Second * 9 / 10)
+	return a.token, nil
+}
+
+func serverBaseUrl(server constant.ServerConfig) string {
+	scheme := server.Scheme
+	if scheme == "" {
+		scheme = "http"
+	}
+	contextPath := server.ContextPath
+	if contextPath == "" {
+		contextPath = constant.DEFAULT_CONTEXT_PATH
+	}
+	return scheme + "://" + server.IpAddr + ":" + strconv.FormatUint(server.Port, 10) + "/" + strings.Trim(contextPath, "/")
+}
@@ -0,0 +1,71 @@
+package nacosstarter
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+const defaultFileExtension = "yaml"
+
+// ConfigTypeOf 根据dataId的扩展名推断配置格式 yml视为yaml，无扩展名时视为text
+func ConfigTypeOf(dataId string) ConfigType {
+	ext := strings.TrimPrefix(path.Ext(dataId), ".")
+	switch ext {
+	case "":
+		return ConfigTypeText
+	case "yml":
+		return ConfigTypeYaml
+	case "txt":
+		return ConfigTypeText
+	}
+	return ConfigType(strings.ToLower(ext))
+}
+
+// ResolveProfileDataIds 按照Spring Cloud Alibaba约定解析dataId
+// 返回 ${app}.${ext} 以及每个环境对应的 ${app}-${profile}.${ext}，顺序即加载顺序
+func ResolveProfileDataIds(application, fileExtension string, profiles []string) []string {
+	if fileExtension == "" {
+		fileExtension = defaultFileExtension
+	}
+	dataIds := []string{application + "." + fileExtension}
+	for _, profile := range profiles {
+		if profile = strings.TrimSpace(profile); profile != "" {
+			dataIds = append(dataIds, application+"-"+profile+"."+fileExtension)
+		}
+	}
+	return dataIds
+}
+
+// 根据应用名称与激活环境为未指定DataId的配置构建分层配置
+// 未激活任何环境时只有一个dataId，直接设置到f上按普通配置加载，返回nil
+func (n *NacosConfig) profileSetting(group string, f *ConfigFileSetting) (*LayeredConfigSetting, error) {
+	if n.ApplicationName == "" {
+		return nil, fmt.Errorf("%w: config setting without dataId requires ApplicationName", ErrInvalidConfig)
+	}
+	dataIds := ResolveProfileDataIds(n.ApplicationName, n.FileExtension, n.ActiveProfiles)
+	if f.Type == "" {
+		f.Type = ConfigTypeOf(dataIds[0])
+	}
+	configType := f.Type
+	if len(dataIds) == 1 {
+		f.DataId = dataIds[0]
+		return nil, nil
+	}
+	sources := make([]ConfigSource, 0, len(dataIds))
+	for _, dataId := range dataIds {
+		sources = append(sources, ConfigSource{Group: group, DataId: dataId, Type: configType})
+	}
+	f.group = group
+	// 合并结果直接应用到原配置上，加载状态可以通过f.Status()获取
+	return &LayeredConfigSetting{
+		Sources:  sources,
+		Type:     configType,
+		Watch:    f.Watch,
+		Value:    f.Value,
+		Validate: f.Validate,
+		OnError:  f.OnError,
+		Debounce: f.Debounce,
+		target:   f,
+	}, nil
+}
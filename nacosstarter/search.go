@@ -0,0 +1,180 @@
+package nacosstarter
+
+import (
+	"fmt"
+	"iter"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/acexy/golang-toolkit/crypto/hashing"
+	"github.com/nacos-group/nacos-sdk-go/v2/vo"
+)
+
+const defaultSearchPageSize = 100
+
+// sdk的搜索结果不包含修改时间 通过v1开放接口查询配置详情获取
+const configDetailPath = "/v1/cs/configs"
+
+// ConfigQuery 配置搜索条件
+type ConfigQuery struct {
+	// dataId与group支持 * 通配符，如 app-*.yaml
+	DataId string
+	// 为空时使用当前客户端的group，搜索所有分组可以使用 *
+	Group string
+	// 模糊搜索 不包含通配符的dataId与group将按包含关系匹配
+	Fuzzy bool
+	// 配置标签
+	Tag string
+	// 所属应用
+	AppName string
+	// 填充结果的最后修改时间 每个结果需要额外请求一次服务端的配置详情
+	WithModified bool
+
+	// 页码从1开始 默认1
+	PageNo int
+	// 每页数量 默认100
+	PageSize int
+}
+
+// ConfigSummary 配置概要信息
+type ConfigSummary struct {
+	Namespace string
+	Group     string
+	DataId    string
+	// 根据dataId的扩展名推断 详见ConfigTypeOf
+	Type    ConfigType
+	Md5     string
+	AppName string
+	// 最后修改时间 仅在ConfigQuery.WithModified为true时填充
+	Modified time.Time
+}
+
+// 配置详情中需要的字段
+type configDetail struct {
+	// unix毫秒
+	ModifyTime int64 `json:"modifyTime"`
+}
+
+// ConfigPage 一页搜索结果
+type ConfigPage struct {
+	Total  int
+	PageNo int
+	Pages  int
+	Items  []ConfigSummary
+}
+
+// Search 按条件分页搜索配置
+func (c *ConfigClient) Search(query ConfigQuery) (ConfigPage, error) {
+	param := c.searchParam(query)
+	page, err := c.client.SearchConfig(param)
+	if err != nil {
+		return ConfigPage{}, serverError(err)
+	}
+	result := ConfigPage{Total: page.TotalCount, PageNo: page.PageNumber, Pages: page.PagesAvailable}
+	for _, item := range page.PageItems {
+		md5 := item.Md5
+		if md5 == "" && item.Content != "" {
+			md5 = hashing.Md5Hex(item.Content)
+		}
+		summary := ConfigSummary{
+			Namespace: c.namespace,
+			Group:     item.Group,
+			DataId:    item.DataId,
+			Type:      ConfigTypeOf(item.DataId),
+			Md5:       md5,
+			AppName:   item.Appname,
+		}
+		if query.WithModified {
+			if summary.Modified, err = c.modifiedTime(item.Group, item.DataId); err != nil {
+				return ConfigPage{}, err
+			}
+		}
+		result.Items = append(result.Items, summary)
+	}
+	return result, nil
+}
+
+// 查询配置的最后修改时间 配置已被删除时返回零值
+func (c *ConfigClient) modifiedTime(group, dataId string) (time.Time, error) {
+	params := url.Values{}
+	params.Set("show", "all")
+	params.Set("dataId", dataId)
+	params.Set("group", group)
+	if c.namespace != "" {
+		params.Set("tenant", c.namespace)
+	}
+	var detail *configDetail
+	if err := c.m.api.request(http.MethodGet, configDetailPath, params, &detail); err != nil {
+		return time.Time{}, fmt.Errorf("query config %s modified time: %w", dataId, err)
+	}
+	if detail == nil || detail.ModifyTime == 0 {
+		return time.Time{}, nil
+	}
+	return time.UnixMilli(detail.ModifyTime), nil
+}
+
+// SearchAll 从query.PageNo开始依次遍历所有页的搜索结果
+// 请求失败时产出一次错误后结束遍历
+func (c *ConfigClient) SearchAll(query ConfigQuery) iter.Seq2[ConfigSummary, error] {
+	return func(yield func(ConfigSummary, error) bool) {
+		if query.PageNo <= 0 {
+			query.PageNo = 1
+		}
+		for {
+			page, err := c.Search(query)
+			if err != nil {
+				yield(ConfigSummary{}, err)
+				return
+			}
+			for _, item := range page.Items {
+				if !yield(item, nil) {
+					return
+				}
+			}
+			if len(page.Items) == 0 || query.PageNo >= page.Pages {
+				return
+			}
+			query.PageNo++
+		}
+	}
+}
+
+func (c *ConfigClient) searchParam(query ConfigQuery) vo.SearchConfigParam {
+	param := vo.SearchConfigParam{
+		Search:   "accurate",
+		DataId:   query.DataId,
+		Group:    query.Group,
+		Tag:      query.Tag,
+		AppName:  query.AppName,
+		PageNo:   query.PageNo,
+		PageSize: query.PageSize,
+	}
+	if param.PageNo <= 0 {
+		param.PageNo = 1
+	}
+	if param.PageSize <= 0 {
+		param.PageSize = defaultSearchPageSize
+	}
+	// 服务端仅在blur模式下将 * 视为通配符
+	if query.Fuzzy || strings.ContainsRune(param.DataId+param.Group, '*') {
+		param.Search = "blur"
+		if query.Fuzzy {
+			param.DataId = fuzzyPattern(param.DataId)
+			param.Group = fuzzyPattern(param.Group)
+		}
+	}
+	if param.Group == "" {
+		param.Group = c.group
+	}
+	return param
+}
+
+// 不包含通配符的模糊条件按包含关系匹配
+func fuzzyPattern(pattern string) string {
+	if pattern == "" || strings.Contains(pattern, "*") {
+		return pattern
+	}
+	return "*" + pattern + "*"
+}
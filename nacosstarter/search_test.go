@@ -0,0 +1,65 @@
+package nacosstarter
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/nacos-group/nacos-sdk-go/v2/common/constant"
+	"github.com/nacos-group/nacos-sdk-go/v2/model"
+	"github.com/nacos-group/nacos-sdk-go/v2/vo"
+)
+
+// 按dataId返回固定结果的搜索
+type searchConfigClient struct {
+	*fakeConfigClient
+}
+
+func (f *searchConfigClient) SearchConfig(param vo.SearchConfigParam) (*model.ConfigPage, error) {
+	page := &model.ConfigPage{TotalCount: 2, PageNumber: param.PageNo, PagesAvailable: 1}
+	for _, dataId := range []string{"app.yaml", "deleted.yaml"} {
+		page.PageItems = append(page.PageItems, model.ConfigItem{DataId: dataId, Group: "DEFAULT_GROUP", Content: "a: 1"})
+	}
+	return page, nil
+}
+
+func TestSearchWithModified(t *testing.T) {
+	modified := time.UnixMilli(1700000000123)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		if r.URL.Path != "/nacos/v1/cs/configs" || query.Get("show") != "all" || query.Get("tenant") != "dev" {
+			http.NotFound(w, r)
+			return
+		}
+		// 不存在的配置返回空内容
+		if query.Get("dataId") == "app.yaml" {
+			_, _ = w.Write([]byte(`{"dataId":"app.yaml","modifyTime":` + strconv.FormatInt(modified.UnixMilli(), 10) + `}`))
+		}
+	}))
+	defer server.Close()
+	host, port, _ := net.SplitHostPort(server.Listener.Addr().String())
+	portNumber, _ := strconv.ParseUint(port, 10, 64)
+
+	c := newTestConfigClient(&searchConfigClient{newFakeConfigClient(nil)})
+	c.namespace = "dev"
+	c.m.serverConfigs = []constant.ServerConfig{{IpAddr: host, Port: portNumber}}
+	c.m.api = newOpenApi(c.m)
+
+	page, err := c.Search(ConfigQuery{DataId: "*.yaml"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !page.Items[0].Modified.IsZero() {
+		t.Errorf("modified filled without WithModified")
+	}
+	page, err = c.Search(ConfigQuery{DataId: "*.yaml", WithModified: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Items) != 2 || !page.Items[0].Modified.Equal(modified) || !page.Items[1].Modified.IsZero() {
+		t.Errorf("items = %+v", page.Items)
+	}
+}
@@ -0,0 +1,123 @@
+package nacosstarter
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/acexy/golang-toolkit/crypto/hashing"
+	"github.com/acexy/golang-toolkit/logger"
+	"github.com/acexy/golang-toolkit/util/json"
+)
+
+const defaultSnapshotRetryInterval = 30 * time.Second
+
+// 本地配置快照 保存的是服务端原始内容(ENC(...)等加密值保持密文)
+type configSnapshot struct {
+	Namespace string `json:"namespace"`
+	Group     string `json:"group"`
+	DataId    string `json:"dataId"`
+	Md5       string `json:"md5"`
+	// 保存时间 unix毫秒
+	Timestamp int64  `json:"timestamp"`
+	Content   string `json:"content"`
+}
+
+func (c *ConfigClient) snapshotPath(dataId string) string {
+	ns := c.namespace
+	if ns == "" {
+		ns = "public"
+	}
+	return filepath.Join(c.m.snapshotDir, url.PathEscape(ns), url.PathEscape(c.group), url.PathEscape(dataId)+".json")
+}
+
+// 保存成功应用的配置内容 未开启快照时忽略
+func (c *ConfigClient) saveSnapshot(dataId, content string) {
+	if c.m.snapshotDir == "" || content == "" {
+		return
+	}
+	file := c.snapshotPath(dataId)
+	data, err := json.ToJsonBytesError(configSnapshot{
+		Namespace: c.namespace,
+		Group:     c.group,
+		DataId:    dataId,
+		Md5:       hashing.Md5Hex(content),
+		Timestamp: time.Now().UnixMilli(),
+		Content:   content,
+	})
+	if err == nil {
+		err = os.MkdirAll(filepath.Dir(file), 0o700)
+	}
+	if err == nil {
+		// 先写临时文件再替换 避免进程退出时留下不完整的快照
+		tmp := file + ".tmp"
+		if err = os.WriteFile(tmp, data, 0o600); err == nil {
+			err = os.Rename(tmp, file)
+		}
+	}
+	if err != nil {
+		logger.Logrus().WithError(err).Warnln("cant save config snapshot:", dataId, "group:", c.group)
+	}
+}
+
+func (c *ConfigClient) loadSnapshot(dataId string) (*configSnapshot, error) {
+	data, err := os.ReadFile(c.snapshotPath(dataId))
+	if err != nil {
+		return nil, err
+	}
+	var snapshot configSnapshot
+	if err = json.ParseBytesError(data, &snapshot); err != nil {
+		return nil, err
+	}
+	// 内容被截断或篡改的快照不可用
+	if snapshot.Md5 != hashing.Md5Hex(snapshot.Content) {
+		return nil, fmt.Errorf("config snapshot %s md5 mismatch", dataId)
+	}
+	return &snapshot, nil
+}
+
+// 获取配置内容 服务端不可用或返回错误时回退到本地快照，此时stale为true
+func (c *ConfigClient) getConfigOrSnapshot(dataId string) (content string, stale bool, err error) {
+	content, err = c.GetConfigRawContent(dataId)
+	if err == nil || c.m.snapshotDir == "" {
+		return content, false, err
+	}
+	snapshot, snapshotErr := c.loadSnapshot(dataId)
+	if snapshotErr != nil {
+		if !os.IsNotExist(snapshotErr) {
+			logger.Logrus().WithError(snapshotErr).Warnln("ignore config snapshot:", dataId, "group:", c.group)
+		}
+		return "", false, err
+	}
+	logger.Logrus().WithError(err).Warnln("load config from server failed, using snapshot:", dataId, "group:", c.group,
+		"saved at:", time.UnixMilli(snapshot.Timestamp).Format(time.RFC3339))
+	return snapshot.Content, true, nil
+}
+
+// 定期尝试从服务端重新获取配置 成功后通过apply替换快照中的值
+func (c *ConfigClient) recoverFromServer(dataId string, apply func(content string) error) {
+	stop := c.m.done
+	go func() {
+		ticker := time.NewTicker(c.m.snapshotRetryInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				content, err := c.GetConfigRawContent(dataId)
+				if err != nil {
+					continue
+				}
+				if err = apply(content); err != nil {
+					logger.Logrus().WithError(err).Errorln("cant apply recovered config:", dataId, "group:", c.group)
+				} else {
+					logger.Logrus().Infoln("config recovered from server:", dataId, "group:", c.group)
+				}
+				return
+			}
+		}
+	}()
+}
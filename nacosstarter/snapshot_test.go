@@ -0,0 +1,39 @@
+package nacosstarter
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestSnapshotMd5(t *testing.T) {
+	c := &ConfigClient{m: &Manager{snapshotDir: t.TempDir()}, group: "DEFAULT_GROUP"}
+	c.saveSnapshot("app.yaml", "name: demo\n")
+	snapshot, err := c.loadSnapshot("app.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if snapshot.Content != "name: demo\n" {
+		t.Errorf("content = %q", snapshot.Content)
+	}
+
+	file := c.snapshotPath("app.yaml")
+	data, _ := os.ReadFile(file)
+	if err = os.WriteFile(file, []byte(strings.Replace(string(data), "demo", "evil", 1)), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = c.loadSnapshot("app.yaml"); err == nil {
+		t.Error("tampered snapshot loaded")
+	}
+}
+
+func TestSnapshotDisablesSdkCache(t *testing.T) {
+	m := &Manager{snapshotDir: t.TempDir()}
+	if !m.clientParam("").ClientConfig.DisableUseSnapShot {
+		t.Error("sdk cache fallback not disabled")
+	}
+	m = &Manager{}
+	if m.clientParam("").ClientConfig.DisableUseSnapShot {
+		t.Error("sdk cache fallback disabled without snapshot dir")
+	}
+}
@@ -0,0 +1,273 @@
+package nacosstarter
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Value 配置中单个键的值
+type Value struct {
+	DataId string
+	Key    string
+	raw    any
+	// 决定Decode使用的tag
+	configType ConfigType
+}
+
+// 已解析的配置树缓存 由WatchConfig在配置变化时失效
+type valueCache struct {
+	watchId string
+	// 每次失效时递增 避免将失效前读取的内容写入缓存
+	generation uint64
+	trees      map[ConfigType]map[string]any
+}
+
+// GetValue 按 a.b[0].c 形式的键路径获取配置中的单个值，适用于json、yaml、properties等所有格式
+// 解析后的配置会被缓存，并在配置变化时自动失效; 键不存在时返回的错误可以匹配ErrKeyNotFound
+func (c *ConfigClient) GetValue(dataId string, configType ConfigType, key string) (Value, error) {
+	segments, err := parseKeyPath(key)
+	if err != nil {
+		return Value{}, err
+	}
+	tree, err := c.cachedTree(dataId, configType)
+	if err != nil {
+		return Value{}, err
+	}
+	raw, ok := lookupTree(tree, segments)
+	if !ok {
+		return Value{}, fmt.Errorf("%w: %s in %s group %s", ErrKeyNotFound, key, dataId, c.group)
+	}
+	return Value{DataId: dataId, Key: key, raw: raw, configType: configType}, nil
+}
+
+func (c *ConfigClient) cachedTree(dataId string, configType ConfigType) (map[string]any, error) {
+	c.valueMu.Lock()
+	cache, ok := c.values[dataId]
+	if ok {
+		if tree, ok := cache.trees[configType]; ok {
+			c.valueMu.Unlock()
+			return tree, nil
+		}
+	}
+	c.valueMu.Unlock()
+
+	if !ok {
+		if err := c.watchValues(dataId); err != nil {
+			return nil, err
+		}
+	}
+	// 并发的watchValues失败或ReleaseValue后缓存可能已被移除 此时仅读取不缓存
+	c.valueMu.Lock()
+	cache = c.values[dataId]
+	var generation uint64
+	if cache != nil {
+		generation = cache.generation
+	}
+	c.valueMu.Unlock()
+
+	raw, err := c.GetConfigRawContent(dataId)
+	if err != nil {
+		return nil, err
+	}
+	if raw == "" {
+		return nil, configNotFound(c.group, dataId)
+	}
+	var tree map[string]any
+	if err = deserializeConfig(raw, configType, &tree); err != nil {
+		return nil, withConfigSource(err, c.group, dataId)
+	}
+	c.valueMu.Lock()
+	defer c.valueMu.Unlock()
+	if cache != nil && c.values[dataId] == cache && cache.generation == generation {
+		cache.trees[configType] = tree
+	}
+	return tree, nil
+}
+
+// 首次读取某个dataId时开始监听 配置变化后清空该dataId的所有缓存
+func (c *ConfigClient) watchValues(dataId string) error {
+	c.valueMu.Lock()
+	if c.values == nil {
+		c.values = make(map[string]*valueCache)
+	}
+	if _, ok := c.values[dataId]; ok {
+		c.valueMu.Unlock()
+		return nil
+	}
+	cache := &valueCache{trees: make(map[ConfigType]map[string]any)}
+	c.values[dataId] = cache
+	c.valueMu.Unlock()
+
+	watchId, err := c.WatchConfig(dataId, func(namespace, group, dataId, data string) {
+		c.valueMu.Lock()
+		defer c.valueMu.Unlock()
+		cache.generation++
+		clear(cache.trees)
+	})
+	c.valueMu.Lock()
+	current := c.values[dataId]
+	if err != nil {
+		if current == cache {
+			delete(c.values, dataId)
+		}
+		c.valueMu.Unlock()
+		return err
+	}
+	cache.watchId = watchId
+	c.valueMu.Unlock()
+	// 监听建立期间已被ReleaseValue释放
+	if current != cache {
+		return c.UnwatchConfig(watchId)
+	}
+	return nil
+}
+
+// ReleaseValue 清空GetValue对指定dataId的缓存并取消对应的监听 再次调用GetValue时将重新加载
+func (c *ConfigClient) ReleaseValue(dataId string) error {
+	c.valueMu.Lock()
+	cache, ok := c.values[dataId]
+	if ok {
+		delete(c.values, dataId)
+	}
+	c.valueMu.Unlock()
+	if !ok || cache.watchId == "" {
+		return nil
+	}
+	return c.UnwatchConfig(cache.watchId)
+}
+
+// Exists 键是否存在且不为null
+func (v Value) Exists() bool {
+	return v.raw != nil
+}
+
+// Raw 反序列化得到的原始值 map[string]any、[]any或标量
+// 返回的是缓存的副本，修改不会影响其他调用方
+func (v Value) Raw() any {
+	return copyTree(v.raw)
+}
+
+func copyTree(node any) any {
+	switch v := node.(type) {
+	case map[string]any:
+		m := make(map[string]any, len(v))
+		for k, child := range v {
+			m[k] = copyTree(child)
+		}
+		return m
+	case []any:
+		list := make([]any, len(v))
+		for i, child := range v {
+			list[i] = copyTree(child)
+		}
+		return list
+	}
+	return node
+}
+
+// String 获取字符串值 数字与布尔值将被格式化为字符串
+func (v Value) String() (string, error) {
+	switch raw := v.raw.(type) {
+	case string:
+		return raw, nil
+	case int, int64, uint64, float64, bool:
+		return fmt.Sprint(raw), nil
+	}
+	return "", v.typeError("string")
+}
+
+// Int 获取整数值 字符串将被解析为整数
+func (v Value) Int() (int, error) {
+	switch raw := v.raw.(type) {
+	case int:
+		return raw, nil
+	case int64:
+		return int(raw), nil
+	case uint64:
+		if raw <= math.MaxInt {
+			return int(raw), nil
+		}
+	case float64:
+		if raw == math.Trunc(raw) {
+			return int(raw), nil
+		}
+	case string:
+		if i, err := strconv.Atoi(raw); err == nil {
+			return i, nil
+		}
+	}
+	return 0, v.typeError("int")
+}
+
+// Float 获取浮点数值 字符串将被解析为浮点数
+func (v Value) Float() (float64, error) {
+	switch raw := v.raw.(type) {
+	case float64:
+		return raw, nil
+	case int:
+		return float64(raw), nil
+	case int64:
+		return float64(raw), nil
+	case uint64:
+		return float64(raw), nil
+	case string:
+		if f, err := strconv.ParseFloat(raw, 64); err == nil {
+			return f, nil
+		}
+	}
+	return 0, v.typeError("float")
+}
+
+// Bool 获取布尔值 字符串按strconv.ParseBool解析
+func (v Value) Bool() (bool, error) {
+	switch raw := v.raw.(type) {
+	case bool:
+		return raw, nil
+	case string:
+		if b, err := strconv.ParseBool(raw); err == nil {
+			return b, nil
+		}
+	}
+	return false, v.typeError("bool")
+}
+
+// Duration 获取时间间隔 字符串按time.ParseDuration解析如 30s，数字视为毫秒
+func (v Value) Duration() (time.Duration, error) {
+	if s, ok := v.raw.(string); ok {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return 0, v.typeError("duration")
+		}
+		return d, nil
+	}
+	ms, err := v.Int()
+	if err != nil {
+		return 0, v.typeError("duration")
+	}
+	return time.Duration(ms) * time.Millisecond, nil
+}
+
+// Decode 将值反序列化到target json格式的配置使用json tag，其他格式使用yaml tag
+func (v Value) Decode(target any) error {
+	if v.configType == ConfigTypeJson {
+		data, err := json.Marshal(v.raw)
+		if err != nil {
+			return err
+		}
+		return json.Unmarshal(data, target)
+	}
+	data, err := yaml.Marshal(v.raw)
+	if err != nil {
+		return err
+	}
+	return yaml.Unmarshal(data, target)
+}
+
+func (v Value) typeError(want string) error {
+	return fmt.Errorf("config %s key %s: cannot convert %T to %s", v.DataId, v.Key, v.raw, want)
+}
@@ -0,0 +1,128 @@
+package nacosstarter
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/nacos-group/nacos-sdk-go/v2/clients/config_client"
+	"github.com/nacos-group/nacos-sdk-go/v2/vo"
+)
+
+// 不依赖服务端的配置sdk 仅实现测试用到的方法
+type fakeConfigClient struct {
+	config_client.IConfigClient
+	mu        sync.Mutex
+	contents  map[string]string
+	listeners map[string]func(namespace, group, dataId, data string)
+	gets      int
+}
+
+func newFakeConfigClient(contents map[string]string) *fakeConfigClient {
+	return &fakeConfigClient{contents: contents, listeners: make(map[string]func(namespace, group, dataId, data string))}
+}
+
+func (f *fakeConfigClient) GetConfig(param vo.ConfigParam) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.gets++
+	return f.contents[param.DataId], nil
+}
+
+func (f *fakeConfigClient) ListenConfig(param vo.ConfigParam) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.listeners[param.DataId] = param.OnChange
+	return nil
+}
+
+func (f *fakeConfigClient) CancelListenConfig(param vo.ConfigParam) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.listeners, param.DataId)
+	return nil
+}
+
+func (f *fakeConfigClient) listening(dataId string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	_, ok := f.listeners[dataId]
+	return ok
+}
+
+func newTestConfigClient(client config_client.IConfigClient) *ConfigClient {
+	m := &Manager{observer: NopObserver{}, done: make(chan struct{})}
+	return &ConfigClient{m: m, group: "DEFAULT_GROUP", client: client, watched: make(map[string]*configWatch), watchIds: make(map[string]string)}
+}
+
+func TestGetValueCache(t *testing.T) {
+	fake := newFakeConfigClient(map[string]string{"app.yaml": "server:\n  port: 8080\n  tags: [a, b]\n"})
+	c := newTestConfigClient(fake)
+
+	v, err := c.GetValue("app.yaml", ConfigTypeYaml, "server")
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw := v.Raw().(map[string]any)
+	raw["port"] = 1
+	raw["tags"].([]any)[0] = "x"
+	port, err := c.GetValue("app.yaml", ConfigTypeYaml, "server.port")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := port.Int(); n != 8080 {
+		t.Errorf("port = %d, cache modified through Raw", n)
+	}
+	tag, _ := c.GetValue("app.yaml", ConfigTypeYaml, "server.tags[0]")
+	if s, _ := tag.String(); s != "a" {
+		t.Errorf("tag = %s, cache modified through Raw", s)
+	}
+	if fake.gets != 1 {
+		t.Errorf("gets = %d, want cached", fake.gets)
+	}
+	if _, err = c.GetValue("app.yaml", ConfigTypeYaml, "server.missing"); !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("err = %v", err)
+	}
+
+	if err = c.ReleaseValue("app.yaml"); err != nil {
+		t.Fatal(err)
+	}
+	if fake.listening("app.yaml") {
+		t.Error("listener kept after ReleaseValue")
+	}
+	if _, err = c.GetValue("app.yaml", ConfigTypeYaml, "server.port"); err != nil {
+		t.Fatal(err)
+	}
+	if fake.gets != 2 || !fake.listening("app.yaml") {
+		t.Errorf("gets = %d, value not reloaded after release", fake.gets)
+	}
+	if err = c.ReleaseValue("unknown.yaml"); err != nil {
+		t.Errorf("release unknown: %v", err)
+	}
+}
+
+func TestGetValueConcurrentRelease(t *testing.T) {
+	fake := newFakeConfigClient(map[string]string{"app.yaml": "a: 1\n"})
+	c := newTestConfigClient(fake)
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			if _, err := c.GetValue("app.yaml", ConfigTypeYaml, "a"); err != nil {
+				t.Error(err)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			_ = c.ReleaseValue("app.yaml")
+		}()
+	}
+	wg.Wait()
+	if err := c.ReleaseValue("app.yaml"); err != nil {
+		t.Fatal(err)
+	}
+	if fake.listening("app.yaml") {
+		t.Error("listener leaked after concurrent release")
+	}
+}
@@ -0,0 +1,83 @@
+package nacosstarter
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// 通过channel投递的事件缓冲大小
+const watchChannelBuffer = 8
+
+// 将监听回调转换为channel 在ctx结束后关闭
+// sdk在独立的goroutine中执行每次回调，事件的先后顺序不保证与变更顺序一致
+// 投递不会阻塞回调，缓冲区已满时丢弃最早的事件，消费方读取到的始终包含最近一次变更
+type eventChannel[T any] struct {
+	ctx    context.Context
+	mu     sync.Mutex
+	closed bool
+	ch     chan T
+}
+
+func newEventChannel[T any](ctx context.Context) *eventChannel[T] {
+	return &eventChannel[T]{ctx: ctx, ch: make(chan T, watchChannelBuffer)}
+}
+
+func (e *eventChannel[T]) send(event T) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.closed {
+		return
+	}
+	for {
+		select {
+		case e.ch <- event:
+			return
+		default:
+		}
+		// 缓冲区已满 丢弃最早的事件后重试
+		select {
+		case <-e.ch:
+		default:
+		}
+	}
+}
+
+// 等待ctx结束后执行cancel并关闭channel
+func (e *eventChannel[T]) closeOnDone(cancel func()) {
+	go func() {
+		<-e.ctx.Done()
+		cancel()
+		e.mu.Lock()
+		defer e.mu.Unlock()
+		e.closed = true
+		close(e.ch)
+	}()
+}
+
+// 合并window内的多次变更 窗口内没有新的变更后使用最后一次的内容执行fn
+// window<=0时直接返回fn
+func debounce(window time.Duration, fn func(content string)) func(content string) {
+	if window <= 0 {
+		return fn
+	}
+	var mu sync.Mutex
+	var timer *time.Timer
+	var latest string
+	fire := func() {
+		mu.Lock()
+		content := latest
+		mu.Unlock()
+		fn(content)
+	}
+	return func(content string) {
+		mu.Lock()
+		defer mu.Unlock()
+		latest = content
+		if timer == nil {
+			timer = time.AfterFunc(window, fire)
+		} else {
+			timer.Reset(window)
+		}
+	}
+}
@@ -0,0 +1,30 @@
+package nacosstarter
+
+import (
+	"context"
+	"testing"
+)
+
+func TestEventChannelKeepsLatest(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	events := newEventChannel[int](ctx)
+	events.closeOnDone(func() {})
+	// 消费方未读取时投递不应阻塞
+	total := watchChannelBuffer * 3
+	for i := 1; i <= total; i++ {
+		events.send(i)
+	}
+	cancel()
+	var got []int
+	for v := range events.ch {
+		got = append(got, v)
+	}
+	if len(got) != watchChannelBuffer {
+		t.Fatalf("got %d events, want %d", len(got), watchChannelBuffer)
+	}
+	if got[0] != total-watchChannelBuffer+1 || got[len(got)-1] != total {
+		t.Errorf("events = %v", got)
+	}
+	// 关闭后投递被忽略
+	events.send(total + 1)
+}
@@ -0,0 +1,134 @@
+package nacosstarter
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/acexy/golang-toolkit/crypto/hashing"
+	"github.com/acexy/golang-toolkit/logger"
+)
+
+// Watched 可热更新的类型化配置
+// 配置值保存在原子指针中，每次变更都会反序列化出全新的值后整体替换，读取方不会与热更新产生竞争
+// 若*T实现了Validatable接口，校验失败的内容不会被应用
+type Watched[T any] struct {
+	client     *ConfigClient
+	dataId     string
+	configType ConfigType
+	watchId    string
+
+	value   atomic.Pointer[T]
+	version atomic.Uint64
+	stale   atomic.Bool
+
+	// 保证多次变更按顺序应用
+	reloadMu sync.Mutex
+	// 最近一次应用的内容md5 相同内容不会重复应用与通知
+	md5        string
+	listenerMu sync.Mutex
+	listeners  []func(old, new T)
+}
+
+// Bind 加载指定配置并监听变化，返回可热更新的配置持有者
+func Bind[T any](group, dataId string, configType ConfigType) (*Watched[T], error) {
+	client, err := GetConfigClient(group)
+	if err != nil {
+		return nil, err
+	}
+	return BindClient[T](client, dataId, configType)
+}
+
+// BindClient 使用指定的配置客户端加载配置并监听变化 用于非默认的Manager或namespace
+func BindClient[T any](client *ConfigClient, dataId string, configType ConfigType) (*Watched[T], error) {
+	group := client.group
+	w := &Watched[T]{client: client, dataId: dataId, configType: configType}
+	raw, stale, err := client.getConfigOrSnapshot(dataId)
+	if err == nil {
+		_, err = w.apply(raw)
+	}
+	client.observeApplied(dataId, false, err)
+	if err != nil {
+		return nil, err
+	}
+	reload := client.reloader(dataId, w.apply, func(err error) {
+		logger.Logrus().WithError(err).Errorln("cant reload config:", dataId, "group:", group)
+	})
+	if stale {
+		w.stale.Store(true)
+		client.recoverFromServer(dataId, reload)
+	} else {
+		client.saveSnapshot(dataId, raw)
+	}
+	w.watchId, err = client.watchReload(dataId, 0, reload)
+	if err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// Get 获取当前配置快照
+// 返回值为快照的浅拷贝，调用方不应修改其中的引用类型字段
+func (w *Watched[T]) Get() T {
+	return *w.value.Load()
+}
+
+// Version 当前配置版本 每次成功应用变更后递增
+func (w *Watched[T]) Version() uint64 {
+	return w.version.Load()
+}
+
+// Stale 当前值是否来自本地快照 从服务端获取到最新内容后恢复为false
+func (w *Watched[T]) Stale() bool {
+	return w.stale.Load()
+}
+
+// OnChange 订阅配置变化 回调将在配置替换完成后按订阅顺序执行
+func (w *Watched[T]) OnChange(listener func(old, new T)) {
+	w.listenerMu.Lock()
+	defer w.listenerMu.Unlock()
+	w.listeners = append(w.listeners, listener)
+}
+
+// Close 取消监听配置变化 已持有的值保持不变
+func (w *Watched[T]) Close() error {
+	return w.client.UnwatchConfig(w.watchId)
+}
+
+// 内容与上一次应用的相同时changed为false
+func (w *Watched[T]) apply(content string) (changed bool, err error) {
+	value := new(T)
+	if content == "" {
+		return false, configNotFound(w.client.group, w.dataId)
+	}
+	contentMd5 := hashing.Md5Hex(content)
+	w.reloadMu.Lock()
+	defer w.reloadMu.Unlock()
+	if contentMd5 == w.md5 {
+		w.stale.Store(false)
+		return false, nil
+	}
+	if err = deserializeConfig(content, w.configType, value); err != nil {
+		return false, withConfigSource(err, w.client.group, w.dataId)
+	}
+	if err = completeFields(value, content, w.configType); err != nil {
+		return false, err
+	}
+	if err = validateConfig(value, nil); err != nil {
+		return false, err
+	}
+	w.md5 = contentMd5
+	var oldValue T
+	if old := w.value.Swap(value); old != nil {
+		oldValue = *old
+	}
+	w.version.Add(1)
+	w.stale.Store(false)
+
+	w.listenerMu.Lock()
+	listeners := append([]func(old, new T){}, w.listeners...)
+	w.listenerMu.Unlock()
+	for _, listener := range listeners {
+		listener(oldValue, *value)
+	}
+	return true, nil
+}
@@ -1,7 +1,10 @@
 package test
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"strings"
 	"testing"
 	"time"
 
@@ -40,6 +43,22 @@ func TestConfig(t *testing.T) {
 	_, _ = loader.StopBySetting()
 }
 
+func TestCodec(t *testing.T) {
+	nacosstarter.RegisterCodec("csv", func(content string, value any) error {
+		*value.(*[]string) = strings.Split(content, ",")
+		return nil
+	}, nil)
+	cc, _ := nacosstarter.GetConfigClient("DEFAULT_GROUP")
+
+	p := YamlConfig{}
+	_ = cc.GetConfig("application.properties", nacosstarter.ConfigTypeProperties, &p)
+	fmt.Printf("application.properties %+v\n", p)
+
+	var c []string
+	_ = cc.GetConfig("list.csv", "csv", &c)
+	fmt.Printf("list.csv %+v\n", c)
+}
+
 func TestWatch(t *testing.T) {
 	cc, _ := nacosstarter.GetConfigClient("DEFAULT_GROUP")
 	watchId, err := cc.WatchConfig("config.json", func(namespace, group, dataId, data string) {
@@ -68,3 +87,299 @@ func TestLoadAndWatch(t *testing.T) {
 		time.Sleep(time.Second * 5)
 	}
 }
+
+func TestPublishConfig(t *testing.T) {
+	cc, _ := nacosstarter.GetConfigClient("DEFAULT_GROUP")
+	flag, err := cc.PublishConfig("publish.json", nacosstarter.ConfigTypeJson, JsonConfig{Config: "v1"})
+	fmt.Println("publish", flag, err)
+
+	var j JsonConfig
+	md5, err := cc.GetConfigWithMd5("publish.json", nacosstarter.ConfigTypeJson, &j)
+	fmt.Printf("publish.json %+v md5 %s %v\n", j, md5, err)
+
+	// 使用过期的md5发布将失败
+	flag, err = cc.PublishConfigCas("publish.json", nacosstarter.ConfigTypeJson, JsonConfig{Config: "v2"}, md5)
+	fmt.Println("cas publish", flag, err)
+	flag, err = cc.PublishConfigCas("publish.json", nacosstarter.ConfigTypeJson, JsonConfig{Config: "v3"}, md5)
+	fmt.Println("stale cas publish", flag, err)
+
+	flag, err = cc.DeleteConfig("publish.json")
+	fmt.Println("delete", flag, err)
+}
+
+func TestUpdateConfig(t *testing.T) {
+	cc, _ := nacosstarter.GetConfigClient("DEFAULT_GROUP")
+	err := nacosstarter.UpdateConfig(cc, "update.yml", nacosstarter.ConfigTypeYaml, func(current *YamlConfig) error {
+		current.Server.Port++
+		return nil
+	})
+	fmt.Println("update", err)
+
+	y := YamlConfig{}
+	_ = cc.GetConfig("update.yml", nacosstarter.ConfigTypeYaml, &y)
+	fmt.Printf("update.yml %+v\n", y)
+}
+
+func TestBind(t *testing.T) {
+	watched, err := nacosstarter.Bind[YamlConfig]("DEFAULT_GROUP", "demo-gateway.yml", nacosstarter.ConfigTypeYaml)
+	if err != nil {
+		fmt.Printf("bind config failed %+v\n", err)
+		return
+	}
+	watched.OnChange(func(old, new YamlConfig) {
+		fmt.Printf("changed %+v -> %+v\n", old, new)
+	})
+	// loop 通过管理中心修改配置 查看是否自动变化
+	for i := 0; i <= 10; i++ {
+		fmt.Printf("version %d %+v\n", watched.Version(), watched.Get())
+		time.Sleep(time.Second * 5)
+	}
+	_ = watched.Close()
+}
+
+func (y *YamlConfig) Validate() error {
+	if y.Server.Port <= 0 || y.Server.Port > 65535 {
+		return fmt.Errorf("bad port %d", y.Server.Port)
+	}
+	return nil
+}
+
+func TestLoadAndWatchValidate(t *testing.T) {
+	cc, _ := nacosstarter.GetConfigClient("DEFAULT_GROUP")
+	var j YamlConfig
+	setting := &nacosstarter.ConfigFileSetting{
+		DataId: "demo-gateway.yml", Type: nacosstarter.ConfigTypeYaml, Watch: true, Value: &j,
+		OnError: func(dataId string, err error) {
+			fmt.Println("rejected", dataId, err)
+		},
+	}
+	cc.LoadAndWatchConfig([]*nacosstarter.ConfigFileSetting{setting})
+	// loop 通过管理中心修改为非法端口 查看配置是否保持不变
+	for i := 0; i <= 10; i++ {
+		fmt.Printf("%+v %+v\n", j, setting.Status())
+		time.Sleep(time.Second * 5)
+	}
+}
+
+func TestLayeredConfig(t *testing.T) {
+	var y YamlConfig
+	setting := &nacosstarter.LayeredConfigSetting{
+		Sources: []nacosstarter.ConfigSource{
+			{Group: "SHARED", DataId: "base.yml", Type: nacosstarter.ConfigTypeYaml},
+			{Group: "DEFAULT_GROUP", DataId: "demo-gateway.yml", Type: nacosstarter.ConfigTypeYaml, Required: true},
+			{Group: "DEFAULT_GROUP", DataId: "demo-gateway-dev.json", Type: nacosstarter.ConfigTypeJson},
+		},
+		Type:  nacosstarter.ConfigTypeYaml,
+		Watch: true,
+		Value: &y,
+	}
+	if err := nacosstarter.LoadAndWatchLayeredConfig(setting); err != nil {
+		fmt.Printf("load layered config failed %+v\n", err)
+		return
+	}
+	// loop 通过管理中心修改任意一层 查看合并结果是否自动变化
+	for i := 0; i <= 10; i++ {
+		fmt.Printf("%+v\n", y)
+		time.Sleep(time.Second * 5)
+	}
+}
+
+func TestLoadWithInterpolate(t *testing.T) {
+	cc, _ := nacosstarter.GetConfigClient("DEFAULT_GROUP")
+	var j YamlConfig
+	// server.port: ${SERVER_PORT:8080}
+	setting := &nacosstarter.ConfigFileSetting{
+		DataId: "demo-gateway.yml", Type: nacosstarter.ConfigTypeYaml, Watch: true, Value: &j,
+		Interpolate: true, StrictPlaceholder: true,
+	}
+	cc.LoadAndWatchConfig([]*nacosstarter.ConfigFileSetting{setting})
+	fmt.Printf("%+v %+v\n", j, setting.Status())
+}
+
+func TestDecryptConfig(t *testing.T) {
+	// 密钥为base64编码的16/24/32字节
+	t.Setenv("NACOS_CONFIG_KEY", "MDEyMzQ1Njc4OWFiY2RlZg==")
+	decryptor, err := nacosstarter.NewAesGcmDecryptorFromEnv("NACOS_CONFIG_KEY")
+	if err != nil {
+		fmt.Printf("create decryptor failed %+v\n", err)
+		return
+	}
+	nacosstarter.SetDecryptor(decryptor)
+	defer nacosstarter.SetDecryptor(nil)
+	value, _ := decryptor.Encrypt("password")
+	fmt.Println("encrypted", value)
+
+	cc, _ := nacosstarter.GetConfigClient("DEFAULT_GROUP")
+	var j JsonConfig
+	// {"config": "ENC(...)"}
+	_ = cc.GetConfig("secret.json", nacosstarter.ConfigTypeJson, &j)
+	fmt.Printf("secret.json %+v\n", j)
+}
+
+type AppConfig struct {
+	Gateway YamlConfig `nacos:"dataId=demo-gateway.yml,watch"`
+	Db      struct {
+		Host string `json:"host" default:"localhost"`
+		Port int    `json:"port" default:"3306"`
+		User string `json:"user" required:"true"`
+	} `nacos:"group=TEST,dataId=db.json,watch,required"`
+}
+
+func TestBindStruct(t *testing.T) {
+	var cfg AppConfig
+	settings, err := nacosstarter.BindStruct(&cfg)
+	if err != nil {
+		fmt.Printf("bind struct failed %+v\n", err)
+	}
+	for _, s := range settings {
+		fmt.Printf("%s %+v\n", s.DataId, s.Status())
+	}
+	fmt.Printf("%+v\n", cfg)
+}
+
+func TestWatchConfigChanges(t *testing.T) {
+	cc, _ := nacosstarter.GetConfigClient("DEFAULT_GROUP")
+	watchId, err := cc.WatchConfigChanges("demo-gateway.yml", nacosstarter.ConfigTypeYaml, func(changes nacosstarter.ChangeSet) {
+		for _, c := range changes.Changes {
+			fmt.Println(c.Type, c.Key, c.OldValue, "->", c.NewValue)
+		}
+		if changes.Changed("server") {
+			fmt.Println("server changed")
+		}
+	})
+	if err != nil {
+		fmt.Printf("watch config failed %+v\n", err)
+		return
+	}
+	time.Sleep(60 * time.Second)
+	_ = cc.UnwatchConfig(watchId)
+}
+
+func TestWatchMultiple(t *testing.T) {
+	cc, _ := nacosstarter.GetConfigClient("DEFAULT_GROUP")
+	first, _ := cc.WatchConfig("config.json", func(namespace, group, dataId, data string) {
+		fmt.Println("first", dataId, data)
+	})
+	second, _ := cc.WatchConfig("config.json", func(namespace, group, dataId, data string) {
+		fmt.Println("second", dataId, data)
+	})
+	time.Sleep(10 * time.Second)
+	fmt.Println("取消第一个订阅者 第二个订阅者继续接收变化")
+	_ = cc.UnwatchConfig(first)
+	time.Sleep(10 * time.Second)
+	_ = cc.UnwatchConfig(second)
+}
+
+func TestWatchConfigChannel(t *testing.T) {
+	cc, _ := nacosstarter.GetConfigClient("DEFAULT_GROUP")
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	events, err := cc.Watch(ctx, "config.json")
+	if err != nil {
+		fmt.Printf("watch config failed %+v\n", err)
+		return
+	}
+	// ctx结束后channel关闭 循环退出
+	for event := range events {
+		fmt.Println(event.DataId, event.Content)
+	}
+}
+
+func TestConfigErrors(t *testing.T) {
+	cc, _ := nacosstarter.GetConfigClient("DEFAULT_GROUP")
+	var j JsonConfig
+	err := cc.GetConfig("not-exists.json", nacosstarter.ConfigTypeJson, &j)
+	fmt.Println("not found:", errors.Is(err, nacosstarter.ErrConfigNotFound), err)
+
+	// 以json格式解析yaml配置 获取出错位置
+	var decodeErr *nacosstarter.DecodeError
+	err = cc.GetConfig("demo-gateway.yml", nacosstarter.ConfigTypeJson, &j)
+	if errors.As(err, &decodeErr) {
+		fmt.Println("decode failed at line", decodeErr.Line, "column", decodeErr.Column, decodeErr.Err)
+	}
+}
+
+func TestNamespaceConfig(t *testing.T) {
+	// 不同namespace使用独立的底层客户端
+	cc, err := nacosstarter.GetNamespaceConfigClient("tenant-a", "DEFAULT_GROUP")
+	if err != nil {
+		fmt.Printf("get namespace client failed %+v\n", err)
+		return
+	}
+	content, err := cc.GetConfigRawContent("config.json")
+	fmt.Println("tenant-a config.json", content, err)
+}
+
+func TestSearchConfig(t *testing.T) {
+	cc, _ := nacosstarter.GetConfigClient("DEFAULT_GROUP")
+	page, err := cc.Search(nacosstarter.ConfigQuery{DataId: "demo-*", PageSize: 10})
+	if err != nil {
+		fmt.Printf("search config failed %+v\n", err)
+		return
+	}
+	fmt.Println("total", page.Total, "pages", page.Pages)
+	// 额外查询每个配置的最后修改时间
+	page, err = cc.Search(nacosstarter.ConfigQuery{DataId: "demo-*", PageSize: 10, WithModified: true})
+	if err != nil {
+		fmt.Printf("search config failed %+v\n", err)
+		return
+	}
+	for _, summary := range page.Items {
+		fmt.Println(summary.DataId, "modified at", summary.Modified)
+	}
+	// 遍历所有分组中dataId包含json的配置
+	for summary, err := range cc.SearchAll(nacosstarter.ConfigQuery{DataId: "json", Group: "*", Fuzzy: true}) {
+		if err != nil {
+			fmt.Printf("search config failed %+v\n", err)
+			return
+		}
+		fmt.Println(summary.Group, summary.DataId, summary.Type, summary.Md5)
+	}
+}
+
+func TestWatchGroup(t *testing.T) {
+	cc, _ := nacosstarter.GetConfigClient("PLUGIN")
+	// 分组中新增的plugin-*.json会被自动监听
+	watchId, err := cc.WatchGroup("plugin-*.json", func(event nacosstarter.GroupEvent) {
+		fmt.Println(event.Type, event.DataId, event.Content)
+	})
+	if err != nil {
+		fmt.Printf("watch group failed %+v\n", err)
+		return
+	}
+	sys.ShutdownCallback(func() {
+		_ = cc.UnwatchGroup(watchId)
+	})
+}
+
+func TestBetaConfig(t *testing.T) {
+	cc, _ := nacosstarter.GetConfigClient("DEFAULT_GROUP")
+	_, err := cc.PublishBeta("beta.json", nacosstarter.ConfigTypeJson, JsonConfig{Config: "beta"}, "127.0.0.1")
+	if err != nil {
+		fmt.Printf("publish beta failed %+v\n", err)
+		return
+	}
+	beta, err := cc.GetBeta("beta.json")
+	fmt.Printf("beta config %+v %v\n", beta, err)
+	_, _ = cc.WatchConfig("beta.json", func(namespace, group, dataId, data string) {
+		isBeta, err := cc.IsBeta(dataId, data)
+		fmt.Println("beta.json changed", data, "beta:", isBeta, err)
+	})
+	time.Sleep(5 * time.Second)
+	flag, err := cc.StopBeta("beta.json")
+	fmt.Println("stop beta", flag, err)
+}
+
+func TestGetValue(t *testing.T) {
+	cc, _ := nacosstarter.GetConfigClient("DEFAULT_GROUP")
+	// 配置变化后缓存自动失效 下次读取即为新值
+	for i := 0; i < 10; i++ {
+		port, err := cc.GetValue("demo-gateway.yml", nacosstarter.ConfigTypeYaml, "server.port")
+		if err != nil {
+			fmt.Printf("get value failed %+v\n", err)
+			return
+		}
+		fmt.Println(port.Int())
+		time.Sleep(time.Second)
+	}
+}
@@ -0,0 +1,26 @@
+package test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/golang-acexy/starter-nacos/nacosstarter/flags"
+)
+
+func TestFlags(t *testing.T) {
+	f, err := flags.Load("DEFAULT_GROUP", "flags.yaml")
+	if err != nil {
+		fmt.Printf("load flags failed %+v\n", err)
+		return
+	}
+	defer f.Close()
+	ctx := flags.WithAttributes(context.Background(), flags.Attributes{"tenant": "t1"})
+	// 修改flags.yaml后无需重启即可生效
+	for i := 0; i < 10; i++ {
+		attrs := flags.Attributes{"userId": fmt.Sprint(i)}
+		fmt.Println("user", i, f.Enabled(ctx, "new-checkout", attrs), f.Variant(ctx, "new-checkout", attrs))
+	}
+	time.Sleep(time.Second)
+}
@@ -0,0 +1,38 @@
+package test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-acexy/starter-nacos/nacosstarter"
+	"github.com/nacos-group/nacos-sdk-go/v2/model"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestMetrics(t *testing.T) {
+	nc, err := nacosstarter.GetNamingClient("DEFAULT_GROUP")
+	if err != nil {
+		fmt.Printf("%+v\n", err)
+		return
+	}
+	_, _ = nc.WatchNaming("go", func(instances []model.Instance, err error) {})
+	_, _ = nc.GetHealthyInstances("go")
+	_, _ = nc.Register(nacosstarter.Instance{Ip: "127.0.0.1", ServiceName: "go", Port: 8081, Weight: 1})
+	time.Sleep(time.Second)
+
+	families, err := prometheus.DefaultGatherer.Gather()
+	if err != nil {
+		fmt.Printf("%+v\n", err)
+		return
+	}
+	for _, f := range families {
+		if !strings.HasPrefix(f.GetName(), "nacos_") {
+			continue
+		}
+		for _, m := range f.GetMetric() {
+			fmt.Println(f.GetName(), m.String())
+		}
+	}
+}
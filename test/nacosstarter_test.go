@@ -8,6 +8,7 @@ import (
 	"github.com/acexy/golang-toolkit/sys"
 	"github.com/acexy/golang-toolkit/util/json"
 	"github.com/golang-acexy/starter-nacos/nacosstarter"
+	"github.com/golang-acexy/starter-nacos/nacosstarter/metrics"
 	"github.com/golang-acexy/starter-parent/parent"
 	"github.com/nacos-group/nacos-sdk-go/v2/common/constant"
 	"github.com/nacos-group/nacos-sdk-go/v2/vo"
@@ -20,6 +21,10 @@ type InitJsonConfig struct {
 }
 
 var initJsonConfig InitJsonConfig
+var profileConfig YamlConfig
+
+// 注册到prometheus.DefaultRegisterer
+var metricsCollector, _ = metrics.Register(nil)
 
 func init() {
 	loader = parent.NewStarterLoader([]parent.Starter{
@@ -43,8 +48,17 @@ func init() {
 					GroupName: "TEST",
 					ConfigSetting: []*nacosstarter.ConfigFileSetting{
 						{DataId: "json.json", Type: nacosstarter.ConfigTypeJson, Watch: true, Value: &initJsonConfig},
+						// 未指定DataId 按约定加载 demo.yaml demo-dev.yaml 1s内的多次修改只应用最后一次
+						{Watch: true, Value: &profileConfig, Debounce: time.Second},
 					},
 				},
+				// nacos不可用时使用本地快照启动
+				SnapshotDir:     "./snapshot",
+				ApplicationName: "demo",
+				ActiveProfiles:  []string{"dev"},
+				// 修改log-level.yaml即可调整日志级别
+				LogLevelSetting: &nacosstarter.LogLevelSetting{Group: "DEFAULT_GROUP", DataId: "log-level.yaml"},
+				Observer:        metricsCollector,
 			},
 		},
 	})
@@ -63,7 +77,7 @@ func TestGetConfig(t *testing.T) {
 			case <-done:
 				return
 			default:
-				fmt.Println(json.ToJson(initJsonConfig))
+				fmt.Println(json.ToJson(initJsonConfig), json.ToJson(profileConfig))
 				time.Sleep(time.Second * 2)
 			}
 		}
@@ -81,3 +95,36 @@ func TestRawNC(t *testing.T) {
 		GroupName:   "WALLET",
 	})
 }
+
+func TestMultiCluster(t *testing.T) {
+	// 同一进程中连接另一个集群 各starter的状态相互独立
+	dr := &nacosstarter.NacosStarter{
+		Config: nacosstarter.NacosConfig{
+			ServerConfig: &nacosstarter.NacosServerConfig{Services: []constant.ServerConfig{
+				{IpAddr: "localhost", Port: 8858},
+			}},
+			ClientConfig: &nacosstarter.NacosClientConfig{
+				ClientConfig: &constant.ClientConfig{
+					Username:            "nacos",
+					Password:            "nacos",
+					LogDir:              "./",
+					CacheDir:            "./",
+					NotLoadCacheAtStart: true,
+				},
+			},
+			DisableDiscovery: true,
+		},
+	}
+	if _, err := dr.Start(); err != nil {
+		fmt.Printf("start dr starter failed %+v\n", err)
+		return
+	}
+	defer dr.Stop(time.Second)
+	cc, _ := dr.Manager().Config("DEFAULT_GROUP")
+	content, err := cc.GetConfigRawContent("config.json")
+	fmt.Println("dr config.json", content, err)
+	// 包级函数仍然作用于第一个启动的starter
+	primary, _ := nacosstarter.GetConfigClient("DEFAULT_GROUP")
+	content, err = primary.GetConfigRawContent("config.json")
+	fmt.Println("primary config.json", content, err)
+}
@@ -1,6 +1,7 @@
 package test
 
 import (
+	"context"
 	"fmt"
 	"testing"
 	"time"
@@ -94,3 +95,34 @@ func TestWatchNaming(t *testing.T) {
 	}()
 	sys.ShutdownHolding()
 }
+
+func TestWatchNamingChannel(t *testing.T) {
+	nc, _ := nacosstarter.GetNamingClient("DEFAULT_GROUP")
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	events, err := nc.Watch(ctx, "go")
+	if err != nil {
+		fmt.Printf("watch naming failed %+v\n", err)
+		return
+	}
+	for event := range events {
+		if event.Err != nil {
+			logger.Logrus().WithError(event.Err).Errorln("watch naming error")
+			continue
+		}
+		logger.Logrus().Traceln(json.ToJson(event.Instances))
+	}
+}
+
+func TestNamespaceRegister(t *testing.T) {
+	nc, err := nacosstarter.GetNamespaceNamingClient("tenant-a", "DEFAULT_GROUP")
+	if err != nil {
+		fmt.Printf("get namespace client failed %+v\n", err)
+		return
+	}
+	_, err = nc.Register(nacosstarter.Instance{Ip: "127.0.0.1", ServiceName: "go", Port: 8091, Weight: 1})
+	if err != nil {
+		fmt.Printf("%+v\n", err)
+	}
+	sys.ShutdownHolding()
+}